@@ -3,23 +3,37 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/korol8484/shortener/internal/app/alias"
+	clickStorage "github.com/korol8484/shortener/internal/app/click/storage"
 	"github.com/korol8484/shortener/internal/app/config"
 	"github.com/korol8484/shortener/internal/app/db"
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	grpcServer "github.com/korol8484/shortener/internal/app/grpc/server"
 	"github.com/korol8484/shortener/internal/app/handlers"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 	"github.com/korol8484/shortener/internal/app/logger"
+	"github.com/korol8484/shortener/internal/app/metrics"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
+	"github.com/korol8484/shortener/internal/app/storage/cache"
 	dbstore "github.com/korol8484/shortener/internal/app/storage/db"
 	"github.com/korol8484/shortener/internal/app/storage/file"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/storage/sqlite"
+	"github.com/korol8484/shortener/internal/app/tracing"
 	userDBStore "github.com/korol8484/shortener/internal/app/user/storage"
 )
 
@@ -33,6 +47,12 @@ var (
 	BuildCommit string = "N/A"
 )
 
+// userRepository creates users for tokens and counts them for internal statistic
+type userRepository interface {
+	middleware.UserAddRepository
+	handlers.UserCounter
+}
+
 func main() {
 	cfg, err := config.NewConfig()
 	if err != nil {
@@ -48,6 +68,14 @@ func main() {
 		_ = zLog.Sync()
 	}(zLog)
 
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err = runMigrate(cfg, args[1:], os.Stdout); err != nil {
+			zLog.Fatal("can't run migrations", zap.Error(err))
+		}
+
+		return
+	}
+
 	if err = run(cfg, zLog); err != nil {
 		if !errors.Is(err, http.ErrServerClosed) {
 			zLog.Fatal("can't run application", zap.Error(err))
@@ -61,36 +89,119 @@ func run(cfg *config.App, log *zap.Logger) error {
 	var store handlers.Store
 	var err error
 	var pingable handlers.Pingable
-	var jwtUserRep middleware.UserAddRepository
+	var jwtUserRep userRepository
+	var clickStore handlers.ClickStore
+	var deleteQueue handlers.DeleteQueue
+
+	m := metrics.New()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, BuildVersion)
+	if err != nil {
+		return err
+	}
+
+	// spans left in batch are flushed after servers and workers are stopped
+	defer func() {
+		withTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
-	if cfg.DBDsn != "" {
-		dbConn, dbErr := db.NewPgDB(cfg)
+		if tErr := shutdownTracing(withTimeout); tErr != nil {
+			log.Error("can't flush spans", zap.Error(tErr))
+		}
+	}()
+
+	if db.IsSQLite(cfg.DBDsn) {
+		dbConn, dbErr := db.NewSQLiteDB(cfg)
 		if dbErr != nil {
 			return dbErr
 		}
 
 		pingable = dbConn
 
+		if err = m.RegisterDB("sqlite", dbConn); err != nil {
+			return err
+		}
+
+		migrator, mErr := newSQLiteMigrator(dbConn)
+		if mErr != nil {
+			return mErr
+		}
+
+		if err = migrator.Up(context.Background()); err != nil {
+			return err
+		}
+
+		sqliteStore, sErr := sqlite.NewStorage(dbConn)
+		if sErr != nil {
+			return sErr
+		}
+
+		store, jwtUserRep = sqliteStore, sqliteStore
+
+		defer func(store handlers.Store) {
+			_ = store.Close()
+		}(store)
+	} else if cfg.DBDsn != "" {
+		pool, pErr := db.NewPgPool(context.Background(), cfg)
+		if pErr != nil {
+			return pErr
+		}
+
+		defer pool.Close()
+
+		if err = m.RegisterPgPool(pool); err != nil {
+			return err
+		}
+
+		// stores on database/sql share connections of pool
+		dbConn := db.SQLFromPool(pool)
+		pingable = dbConn
+
+		migrator, mErr := newMigrator(dbConn)
+		if mErr != nil {
+			return mErr
+		}
+
+		if err = migrator.Up(context.Background()); err != nil {
+			return err
+		}
+
 		jwtUserRep, err = userDBStore.NewStorage(dbConn)
 		if err != nil {
 			return err
 		}
 
-		store, err = dbstore.NewStorage(dbConn)
+		store, err = dbstore.NewStorage(pool)
 		if err != nil {
 			return err
 		}
-	} else if cfg.FileStoragePath != "" {
-		store, err = file.NewFileStore(cfg, memory.NewMemStore())
+
+		clickStore, err = clickStorage.NewStorage(dbConn)
 		if err != nil {
 			return err
 		}
 
+		deleteQueue, err = deleteStorage.NewStorage(dbConn)
+		if err != nil {
+			return err
+		}
+	} else if cfg.FileStoragePath != "" {
+		fStore, fErr := file.NewFileStore(cfg, memory.NewMemStore())
+		if fErr != nil {
+			return fErr
+		}
+
+		store = fStore
+
 		jwtUserRep = userDBStore.NewMemoryStore()
 
 		defer func(store handlers.Store) {
 			_ = store.Close()
 		}(store)
+
+		// stopped before store is closed
+		compactor := file.NewCompactor(fStore, cfg, log)
+		defer compactor.Close()
 	} else {
 		jwtUserRep = userDBStore.NewMemoryStore()
 		store = memory.NewMemStore()
@@ -100,20 +211,158 @@ func run(cfg *config.App, log *zap.Logger) error {
 		pingable = handlers.NewPingDummy()
 	}
 
-	dh, err := handlers.NewDelete(store, log)
+	if clickStore == nil {
+		clickStore = clickStorage.NewMemoryStore()
+	}
+
+	if deleteQueue == nil && cfg.GetDeleteQueuePath() != "" {
+		fQueue, qErr := deleteStorage.NewFileStorage(cfg.GetDeleteQueuePath())
+		if qErr != nil {
+			return qErr
+		}
+
+		deleteQueue = fQueue
+
+		defer func(fQueue *deleteStorage.FileStorage) {
+			_ = fQueue.Close()
+		}(fQueue)
+	} else if deleteQueue == nil {
+		deleteQueue = deleteStorage.NewMemoryStore()
+	}
+
+	// optional capabilities are detected on base store, cache decorator forwards them
+	base := store
+
+	// latency of base store is observed and traced, reads served by cache are not counted
+	store = metrics.NewStore(tracing.NewStore(store), m)
+	jwtUserRep = tracing.NewUserRepository(jwtUserRep)
+
+	if cfg.GetCacheSize() > 0 {
+		cached, cErr := newCache(cfg, store, log)
+		if cErr != nil {
+			return cErr
+		}
+
+		store = cached
+
+		defer func(cached *cache.Store) {
+			_ = cached.Close()
+		}(cached)
+	}
+
+	seq, ok := base.(alias.Sequence)
+	if !ok {
+		seq = alias.NewCounter(0)
+	}
+
+	gen, err := alias.NewGenerator(cfg, seq)
+	if err != nil {
+		return err
+	}
+
+	jwtH, err := middleware.NewJwtFromConfig(metrics.NewUserRepository(jwtUserRep, m), log, cfg)
+	if err != nil {
+		return err
+	}
+
+	trustedSubnet, err := middleware.TrustedSubnet(cfg.GetTrustedSubnet())
+	if err != nil {
+		return err
+	}
+
+	limiter, err := newLimiter(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer func(limiter ratelimit.Limiter) {
+		_ = limiter.Close()
+	}(limiter)
+
+	rateLimit, err := middleware.NewRateLimit(limiter, cfg, log)
+	if err != nil {
+		return err
+	}
+
+	realIP, err := middleware.NewRealIP(cfg.GetTrustedProxies())
 	if err != nil {
 		return err
 	}
 
+	dh, err := handlers.NewDelete(store, deleteQueue, log)
+	if err != nil {
+		return err
+	}
+
+	// queued deletes are drained before stores are closed
 	defer dh.Close()
 
+	if err = m.RegisterDelete(dh); err != nil {
+		return err
+	}
+
+	clicks, err := handlers.NewClicks(clickStore, store, log)
+	if err != nil {
+		return err
+	}
+
+	defer clicks.Close()
+
+	if exp, ok := base.(handlers.Expirable); ok && cfg.ReapInterval > 0 {
+		reaper, rErr := handlers.NewReaper(exp, cfg.ReapInterval, log)
+		if rErr != nil {
+			return rErr
+		}
+
+		defer reaper.Close()
+	}
+
+	// purge goes through cache to invalidate purged links
+	if _, ok := base.(handlers.Purgeable); ok && cfg.GetPurgeInterval() > 0 && cfg.GetDeleteRetention() > 0 {
+		purger, pErr := handlers.NewPurger(
+			store.(handlers.Purgeable), cfg.GetDeleteRetention(), cfg.GetPurgeInterval(), log, clicks,
+		)
+		if pErr != nil {
+			return pErr
+		}
+
+		defer purger.Close()
+	}
+
 	fmt.Printf("Build version: %s\n", BuildVersion)
 	fmt.Printf("Build date: %s\n", BuildDate)
 	fmt.Printf("Build commit: %s\n", BuildCommit)
 
+	// decorators implement all optional capabilities, compaction is available when base store has it
+	var compact handlers.Compactable
+	if _, ok := base.(handlers.Compactable); ok {
+		compact, _ = store.(handlers.Compactable)
+	}
+
+	// client address is resolved before access log and rate limits
+	router := realIP.Handler(handlers.CreateRouter(
+		store, cfg, log, pingable, jwtH, middleware.NewCompressor(cfg), rateLimit, dh, gen, clicks, jwtUserRep, trustedSubnet,
+		middleware.Instrument(m), compact,
+	))
+
+	var adminServer *http.Server
+
+	if cfg.MetricsListen != "" {
+		admin := http.NewServeMux()
+		admin.Handle("/metrics", m.Handler())
+
+		adminServer = &http.Server{Addr: cfg.MetricsListen, Handler: admin}
+	} else {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		mux.Handle("/", router)
+
+		router = mux
+	}
+
 	server := &http.Server{
 		Addr:    cfg.Listen,
-		Handler: handlers.CreateRouter(store, cfg, log, pingable, jwtUserRep, dh),
+		Handler: router,
 	}
 
 	oss, stop, errCh := make(chan os.Signal, 1), make(chan struct{}, 1), make(chan error, 1)
@@ -142,12 +391,56 @@ func run(cfg *config.App, log *zap.Logger) error {
 		}()
 	}
 
+	if adminServer != nil {
+		go func() {
+			if aErr := adminServer.ListenAndServe(); aErr != nil {
+				errCh <- aErr
+			}
+		}()
+	}
+
+	var gServer *grpc.Server
+	if cfg.GRPCListen != "" {
+		gServer, err = newGRPCServer(cfg, log, store, pingable, jwtH, dh, gen, limiter, realIP)
+		if err != nil {
+			return err
+		}
+
+		listen, lErr := net.Listen("tcp", cfg.GRPCListen)
+		if lErr != nil {
+			return lErr
+		}
+
+		go func() {
+			if gErr := gServer.Serve(listen); gErr != nil {
+				errCh <- gErr
+			}
+		}()
+	}
+
 	for {
 		select {
 		case e := <-errCh:
+			if gServer != nil {
+				gServer.Stop()
+			}
+
+			if adminServer != nil {
+				_ = adminServer.Close()
+			}
+
 			return e
 		case <-stop:
+			if gServer != nil {
+				gServer.GracefulStop()
+			}
+
 			withTimeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+			if adminServer != nil {
+				_ = adminServer.Shutdown(withTimeout)
+			}
+
 			if err = server.Shutdown(withTimeout); err != nil {
 				cancel()
 
@@ -160,3 +453,78 @@ func run(cfg *config.App, log *zap.Logger) error {
 		}
 	}
 }
+
+// newCache caching decorator of store with redis tier when it is configured
+func newCache(cfg *config.App, store handlers.Store, log *zap.Logger) (*cache.Store, error) {
+	var remote cache.Remote
+
+	if cfg.GetCacheRedisURL() != "" {
+		r, err := cache.NewRedis(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can't connect to redis cache: %w", err)
+		}
+
+		remote = r
+	}
+
+	cached, err := cache.NewStore(store, cfg, remote, log)
+	if err != nil {
+		if remote != nil {
+			_ = remote.Close()
+		}
+
+		return nil, err
+	}
+
+	return cached, nil
+}
+
+// newLimiter rate limit buckets shared by instances in redis or kept in process
+func newLimiter(cfg *config.App) (ratelimit.Limiter, error) {
+	if cfg.GetRateLimitRedisURL() == "" {
+		return ratelimit.NewMemory(), nil
+	}
+
+	limiter, err := ratelimit.NewRedis(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to redis rate limit: %w", err)
+	}
+
+	return limiter, nil
+}
+
+func newGRPCServer(
+	cfg *config.App,
+	log *zap.Logger,
+	store handlers.Store,
+	pingable handlers.Pingable,
+	jwtH *middleware.Jwt,
+	dh *handlers.Delete,
+	gen alias.Generator,
+	limiter ratelimit.Limiter,
+	realIP *middleware.RealIP,
+) (*grpc.Server, error) {
+	auth := grpcServer.NewAuth(jwtH, log)
+
+	rateLimit, err := grpcServer.NewRateLimit(limiter, cfg, realIP, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// calls are limited by client IP before user is set and by user after
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(rateLimit.UnaryIP(), auth.Unary(), rateLimit.UnaryUser())}
+
+	if cfg.HTTPS.Enable {
+		creds, err := credentials.NewServerTLSFromFile(cfg.HTTPS.Pem, cfg.HTTPS.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	s := grpc.NewServer(opts...)
+	pb.RegisterShortenerServer(s, grpcServer.NewServer(store, cfg, pingable, dh, gen))
+
+	return s, nil
+}
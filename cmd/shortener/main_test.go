@@ -19,10 +19,10 @@ func Test_run(t *testing.T) {
 		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 	}()
 
-	err := run(&config.App{HTTPS: &config.HTTPS{}, Listen: ":8099"}, zap.L())
+	err := run(&config.App{HTTPS: &config.HTTPS{}, Listen: ":8099", JWTKeys: ":HS256:123"}, zap.L())
 	require.NoError(t, err)
 
-	err = run(&config.App{HTTPS: &config.HTTPS{}, Listen: "8099"}, zap.L())
+	err = run(&config.App{HTTPS: &config.HTTPS{}, Listen: "8099", JWTKeys: ":HS256:123"}, zap.L())
 	require.Error(t, err)
 
 	go func() {
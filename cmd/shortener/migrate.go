@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	clickStorage "github.com/korol8484/shortener/internal/app/click/storage"
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/db"
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
+	"github.com/korol8484/shortener/internal/app/migrations"
+	dbstore "github.com/korol8484/shortener/internal/app/storage/db"
+	"github.com/korol8484/shortener/internal/app/storage/sqlite"
+	userDBStore "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+const migrateUsage = "usage: shortener -d <dsn> migrate up|down [steps]|status"
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+// newMigrator create migrator with registered migrations of all postgresql stores,
+// user store goes first as shortener tables reference it
+func newMigrator(dbConn *sql.DB) (*migrations.Migrator, error) {
+	m := migrations.NewMigrator(dbConn)
+
+	if err := userDBStore.RegisterMigrations(m); err != nil {
+		return nil, err
+	}
+
+	if err := dbstore.RegisterMigrations(m); err != nil {
+		return nil, err
+	}
+
+	if err := clickStorage.RegisterMigrations(m); err != nil {
+		return nil, err
+	}
+
+	if err := deleteStorage.RegisterMigrations(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// newSQLiteMigrator create migrator with registered migrations of sqlite store
+func newSQLiteMigrator(dbConn *sql.DB) (*migrations.Migrator, error) {
+	m := migrations.NewSQLiteMigrator(dbConn)
+
+	if err := sqlite.RegisterMigrations(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// openMigrator connect to database of DSN and create migrator of it
+func openMigrator(cfg *config.App) (*sql.DB, *migrations.Migrator, error) {
+	var (
+		dbConn *sql.DB
+		m      *migrations.Migrator
+		err    error
+	)
+
+	if db.IsSQLite(cfg.DBDsn) {
+		if dbConn, err = db.NewSQLiteDB(cfg); err != nil {
+			return nil, nil, err
+		}
+
+		m, err = newSQLiteMigrator(dbConn)
+	} else {
+		if dbConn, err = db.NewPgDB(cfg); err != nil {
+			return nil, nil, err
+		}
+
+		m, err = newMigrator(dbConn)
+	}
+
+	if err != nil {
+		_ = dbConn.Close()
+		return nil, nil, err
+	}
+
+	return dbConn, m, nil
+}
+
+// runMigrate - migrate subcommand: up, down [steps], status
+func runMigrate(cfg *config.App, args []string, out io.Writer) error {
+	if len(args) < 1 {
+		return errMigrateUsage
+	}
+
+	if cfg.DBDsn == "" {
+		return errors.New("migrate requires postgresql or sqlite DSN")
+	}
+
+	dbConn, m, err := openMigrator(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer func(dbConn *sql.DB) {
+		_ = dbConn.Close()
+	}(dbConn)
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		return m.Down(ctx, steps)
+	case "status":
+		var statuses []*migrations.Status
+
+		statuses, err = m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+		for _, st := range statuses {
+			state, at := "pending", ""
+			if st.Applied {
+				state, at = "applied", st.AppliedAt.Format(time.RFC3339)
+			}
+
+			_, _ = fmt.Fprintf(w, "%05d\t%s\t%s\t%s\n", st.Version, st.Name, state, at)
+		}
+
+		return w.Flush()
+	default:
+		return errMigrateUsage
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/config"
+)
+
+func Test_runMigrate(t *testing.T) {
+	var out bytes.Buffer
+
+	err := runMigrate(&config.App{DBDsn: "postgresql://localhost/short"}, nil, &out)
+	require.ErrorIs(t, err, errMigrateUsage)
+
+	err = runMigrate(&config.App{}, []string{"up"}, &out)
+	require.Error(t, err)
+
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "short.db")
+
+	require.NoError(t, runMigrate(&config.App{DBDsn: dsn}, []string{"up"}, &out))
+	require.NoError(t, runMigrate(&config.App{DBDsn: dsn}, []string{"status"}, &out))
+	assert.Regexp(t, `00001\s+create_schema\s+applied`, out.String())
+}
+
+func Test_newMigrator(t *testing.T) {
+	_, err := newMigrator(nil)
+	require.NoError(t, err)
+
+	_, err = newSQLiteMigrator(nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+// Package alias strategies to generate short URL alias
+package alias
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Alias generation strategies
+const (
+	// StrategyHash - deterministic hash of URL, salted by attempt on collision
+	StrategyHash = "hash"
+	// StrategyRandom - crypto random alias
+	StrategyRandom = "random"
+	// StrategySequence - base62 encoded sequence value
+	StrategySequence = "sequence"
+	// StrategyHashids - hashids encoded sequence value
+	StrategyHashids = "hashids"
+)
+
+// DefaultLength alias length used when config has none
+const DefaultLength = 6
+
+// ErrUnknownStrategy - Ошибка что стратегия генерации не поддерживается
+var ErrUnknownStrategy = errors.New("unknown alias strategy")
+
+// Generator creates alias for URL, attempt grows on each retry after alias collision
+type Generator interface {
+	Generate(ctx context.Context, URL string, attempt int) (string, error)
+}
+
+// Sequence source of unique increasing IDs
+type Sequence interface {
+	NextID(ctx context.Context) (int64, error)
+}
+
+// Config alias generation configuration
+type Config interface {
+	GetAliasStrategy() string
+	GetAliasLength() int
+	GetAliasSalt() string
+}
+
+// NewGenerator Factory, seq used by sequence and hashids strategies
+func NewGenerator(cfg Config, seq Sequence) (Generator, error) {
+	length := cfg.GetAliasLength()
+	if length < 1 {
+		length = DefaultLength
+	}
+
+	switch cfg.GetAliasStrategy() {
+	case "", StrategyHash:
+		return NewHash(length), nil
+	case StrategyRandom:
+		return NewRandom(length), nil
+	case StrategySequence:
+		return NewBase62(length, seq), nil
+	case StrategyHashids:
+		return NewHashids(length, cfg.GetAliasSalt(), seq)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, cfg.GetAliasStrategy())
+	}
+}
@@ -0,0 +1,143 @@
+package alias
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	strategy string
+	length   int
+	salt     string
+}
+
+func (c testConfig) GetAliasStrategy() string {
+	return c.strategy
+}
+
+func (c testConfig) GetAliasLength() int {
+	return c.length
+}
+
+func (c testConfig) GetAliasSalt() string {
+	return c.salt
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     Generator
+	}{
+		{strategy: "", want: &Hash{}},
+		{strategy: StrategyHash, want: &Hash{}},
+		{strategy: StrategyRandom, want: &Random{}},
+		{strategy: StrategySequence, want: &Base62{}},
+		{strategy: StrategyHashids, want: &Hashids{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			gen, err := NewGenerator(testConfig{strategy: test.strategy}, NewCounter(0))
+			require.NoError(t, err)
+			assert.IsType(t, test.want, gen)
+
+			a, err := gen.Generate(context.Background(), "http://www.ya.ru", 0)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, len(a), DefaultLength)
+		})
+	}
+
+	_, err := NewGenerator(testConfig{strategy: "md5"}, nil)
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestHash_Generate(t *testing.T) {
+	gen := NewHash(6)
+
+	a, err := gen.Generate(context.Background(), "testString", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Jlf8iW", a)
+
+	retry, err := gen.Generate(context.Background(), "testString", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, retry)
+}
+
+func TestRandom_Generate(t *testing.T) {
+	gen := NewRandom(8)
+
+	a, err := gen.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Len(t, a, 8)
+
+	b, err := gen.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
+
+func TestBase62_Generate(t *testing.T) {
+	gen := NewBase62(3, NewCounter(61))
+
+	a, err := gen.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "aba", a)
+
+	assert.Equal(t, "9", EncodeBase62(61, 0))
+	assert.Equal(t, "aaa", EncodeBase62(0, 3))
+}
+
+func TestHashids_Generate(t *testing.T) {
+	gen, err := NewHashids(8, "salt", NewCounter(0))
+	require.NoError(t, err)
+
+	a, err := gen.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Len(t, a, 8)
+
+	b, err := gen.Generate(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
+
+func BenchmarkHash_Generate(b *testing.B) {
+	gen := NewHash(5)
+
+	for i := 0; i < b.N; i++ {
+		_, _ = gen.Generate(context.Background(), "https://ya.ru", 0)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		alias string
+		want  error
+	}{
+		{alias: "spring-sale", want: nil},
+		{alias: "Spring_Sale_2024", want: nil},
+		{alias: "ab", want: ErrInvalidAlias},
+		{alias: strings.Repeat("a", MaxCustomLength+1), want: ErrInvalidAlias},
+		{alias: "spring sale", want: ErrInvalidAlias},
+		{alias: "spring/sale", want: ErrInvalidAlias},
+		{alias: "весна", want: ErrInvalidAlias},
+		{alias: "api", want: ErrReservedAlias},
+		{alias: "Ping", want: ErrReservedAlias},
+		{alias: "metrics", want: ErrReservedAlias},
+	}
+
+	for _, test := range tests {
+		t.Run(test.alias, func(t *testing.T) {
+			err := Validate(test.alias)
+			if test.want == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, test.want)
+			assert.True(t, IsInvalid(err))
+		})
+	}
+}
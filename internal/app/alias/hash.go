@@ -0,0 +1,42 @@
+package alias
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+)
+
+// Hash deterministic alias generator, same URL gets same alias on first attempt
+type Hash struct {
+	length int
+}
+
+// NewHash Factory
+func NewHash(length int) *Hash {
+	return &Hash{length: length}
+}
+
+// Generate - Create alias as a hash of the URL, attempt salts hash after collision
+func (h *Hash) Generate(_ context.Context, URL string, attempt int) (string, error) {
+	if attempt > 0 {
+		URL += "#" + strconv.Itoa(attempt)
+	}
+
+	return FromHash(h.length, URL), nil
+}
+
+// FromHash - Create alias length n as a hash of the string
+func FromHash(keyLen int, s string) string {
+	h := fnv.New64()
+	h.Write([]byte(s))
+
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	keyMap := make([]byte, keyLen)
+	for i := range keyMap {
+		keyMap[i] = charset[r.Intn(len(charset))]
+	}
+
+	return string(keyMap)
+}
@@ -0,0 +1,37 @@
+package alias
+
+import (
+	"context"
+
+	"github.com/speps/go-hashids/v2"
+)
+
+// Hashids alias generator, encode next sequence value with hashids
+type Hashids struct {
+	hd  *hashids.HashID
+	seq Sequence
+}
+
+// NewHashids Factory, length is minimal alias length
+func NewHashids(length int, salt string, seq Sequence) (*Hashids, error) {
+	hd := hashids.NewData()
+	hd.Salt = salt
+	hd.MinLength = length
+
+	h, err := hashids.NewWithData(hd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hashids{hd: h, seq: seq}, nil
+}
+
+// Generate - Create alias from next sequence value, URL is ignored
+func (h *Hashids) Generate(ctx context.Context, _ string, _ int) (string, error) {
+	id, err := h.seq.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return h.hd.EncodeInt64([]int64{id})
+}
@@ -0,0 +1,44 @@
+package alias
+
+import (
+	"context"
+	"crypto/rand"
+)
+
+// Random crypto random alias generator
+type Random struct {
+	length int
+}
+
+// NewRandom Factory
+func NewRandom(length int) *Random {
+	return &Random{length: length}
+}
+
+// Generate - Create random alias, URL is ignored
+func (r *Random) Generate(_ context.Context, _ string, _ int) (string, error) {
+	// bytes above limit would make first chars of charset more probable
+	const limit = 256 - 256%len(charset)
+
+	keyMap := make([]byte, 0, r.length)
+	buf := make([]byte, r.length)
+
+	for len(keyMap) < r.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+
+			keyMap = append(keyMap, charset[int(b)%len(charset)])
+			if len(keyMap) == r.length {
+				break
+			}
+		}
+	}
+
+	return string(keyMap), nil
+}
@@ -0,0 +1,63 @@
+package alias
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// Base62 alias generator, encode next sequence value in base62
+type Base62 struct {
+	length int
+	seq    Sequence
+}
+
+// NewBase62 Factory, length is minimal alias length
+func NewBase62(length int, seq Sequence) *Base62 {
+	return &Base62{length: length, seq: seq}
+}
+
+// Generate - Create alias from next sequence value, URL is ignored
+func (b *Base62) Generate(ctx context.Context, _ string, _ int) (string, error) {
+	id, err := b.seq.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeBase62(id, b.length), nil
+}
+
+// EncodeBase62 encode positive number in base62, left padded to minLen by zero digit
+func EncodeBase62(n int64, minLen int) string {
+	var buf []byte
+
+	for n > 0 {
+		buf = append(buf, charset[n%int64(len(charset))])
+		n /= int64(len(charset))
+	}
+
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+
+	if len(buf) < minLen {
+		return strings.Repeat(charset[:1], minLen-len(buf)) + string(buf)
+	}
+
+	return string(buf)
+}
+
+// Counter in memory Sequence
+type Counter struct {
+	id int64
+}
+
+// NewCounter Factory, first NextID returns start + 1
+func NewCounter(start int64) *Counter {
+	return &Counter{id: start}
+}
+
+// NextID return next counter value
+func (c *Counter) NextID(_ context.Context) (int64, error) {
+	return atomic.AddInt64(&c.id, 1), nil
+}
@@ -0,0 +1,50 @@
+package alias
+
+import (
+	"errors"
+	"strings"
+)
+
+// Custom alias length limits
+const (
+	MinCustomLength = 3
+	MaxCustomLength = 32
+)
+
+// reserved aliases conflict with service routes, metrics is mounted on main listener
+var reserved = map[string]struct{}{
+	"api":     {},
+	"metrics": {},
+	"ping":    {},
+}
+
+var (
+	// ErrInvalidAlias - Ошибка что alias содержит недопустимые символы или неверной длины
+	ErrInvalidAlias = errors.New("alias must be 3-32 characters of a-z, A-Z, 0-9, '-' or '_'")
+	// ErrReservedAlias - Ошибка что alias зарезервирован
+	ErrReservedAlias = errors.New("alias is reserved")
+)
+
+// Validate check custom alias characters, length and reserved words
+func Validate(a string) error {
+	if len(a) < MinCustomLength || len(a) > MaxCustomLength {
+		return ErrInvalidAlias
+	}
+
+	for _, c := range a {
+		if !strings.ContainsRune(charset, c) && c != '-' && c != '_' {
+			return ErrInvalidAlias
+		}
+	}
+
+	if _, ok := reserved[strings.ToLower(a)]; ok {
+		return ErrReservedAlias
+	}
+
+	return nil
+}
+
+// IsInvalid check error is custom alias validation error
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrInvalidAlias) || errors.Is(err, ErrReservedAlias)
+}
@@ -0,0 +1,109 @@
+// Package storage click events storage
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
+)
+
+// queries take values as arrays, number of bind parameters doesn't depend on batch size
+const (
+	queryAddClicks = `INSERT INTO click (alias, clicked_at, referrer, user_agent, ip)
+		SELECT * FROM unnest($1::text[], $2::timestamptz[], $3::text[], $4::text[], $5::text[])`
+	queryDeleteClicks = `DELETE FROM click WHERE alias = ANY($1::text[])`
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// DBStorage Click storage
+type DBStorage struct {
+	db *sql.DB
+}
+
+// NewStorage - Click storage factory, schema must be created by RegisterMigrations
+func NewStorage(db *sql.DB) (*DBStorage, error) {
+	return &DBStorage{db: db}, nil
+}
+
+// RegisterMigrations register click schema migrations
+func RegisterMigrations(m *migrations.Migrator) error {
+	return m.Register(migrationFS, "migrations")
+}
+
+// AddClicks save collection of clicks with one insert
+func (d *DBStorage) AddClicks(ctx context.Context, clicks []*domain.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	var (
+		aliases    = make([]string, 0, len(clicks))
+		at         = make([]time.Time, 0, len(clicks))
+		referrers  = make([]string, 0, len(clicks))
+		userAgents = make([]string, 0, len(clicks))
+		ips        = make([]string, 0, len(clicks))
+	)
+
+	for _, v := range clicks {
+		aliases = append(aliases, v.Alias)
+		at = append(at, v.At)
+		referrers = append(referrers, v.Referrer)
+		userAgents = append(userAgents, v.UserAgent)
+		ips = append(ips, v.IP)
+	}
+
+	_, err := d.db.ExecContext(ctx, queryAddClicks, aliases, at, referrers, userAgents, ips)
+
+	return err
+}
+
+// DeleteClicks remove clicks of aliases with one delete
+func (d *DBStorage) DeleteClicks(ctx context.Context, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	_, err := d.db.ExecContext(ctx, queryDeleteClicks, aliases)
+
+	return err
+}
+
+// Stats return total and per day UTC clicks of alias
+func (d *DBStorage) Stats(ctx context.Context, alias string) (*domain.ClickStats, error) {
+	rows, err := d.db.QueryContext(
+		ctx,
+		`SELECT date_trunc('day', clicked_at AT TIME ZONE 'UTC') AS day, count(*) FROM click
+		WHERE alias = $1 GROUP BY day ORDER BY day;`,
+		alias,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stats := &domain.ClickStats{}
+	for rows.Next() {
+		var day domain.DailyClicks
+
+		if err = rows.Scan(&day.Day, &day.Count); err != nil {
+			return nil, err
+		}
+
+		day.Day = time.Date(day.Day.Year(), day.Day.Month(), day.Day.Day(), 0, 0, 0, 0, time.UTC)
+		stats.Total += day.Count
+		stats.Daily = append(stats.Daily, day)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
+)
+
+// arrayConverter pass slices to driver as is, pgx driver encodes them as postgres arrays
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v any) (driver.Value, error) {
+	switch v.(type) {
+	case []string, []time.Time:
+		return v, nil
+	}
+
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestDBStorage_AddClicks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := NewStorage(db)
+	require.NoError(t, err)
+
+	at := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT * FROM unnest($1::text[], $2::timestamptz[], $3::text[], $4::text[], $5::text[])")).
+		WithArgs(
+			[]string{"7A2S4z", "7A1S4z"},
+			[]time.Time{at, at},
+			[]string{"http://ya.ru", ""},
+			[]string{"curl", ""},
+			[]string{"10.0.0.1", ""},
+		).
+		WillReturnResult(sqlmock.NewResult(2, 2))
+
+	err = store.AddClicks(context.Background(), []*domain.Click{
+		{Alias: "7A2S4z", At: at, Referrer: "http://ya.ru", UserAgent: "curl", IP: "10.0.0.1"},
+		{Alias: "7A1S4z", At: at},
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.AddClicks(context.Background(), nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBStorage_DeleteClicks(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := NewStorage(db)
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM click WHERE alias = ANY($1::text[])")).
+		WithArgs([]string{"7A2S4z", "7A1S4z"}).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	require.NoError(t, store.DeleteClicks(context.Background(), []string{"7A2S4z", "7A1S4z"}))
+	require.NoError(t, store.DeleteClicks(context.Background(), nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBStorage_Stats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := NewStorage(db)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT date_trunc").
+		WithArgs("7A2S4z").
+		WillReturnRows(sqlmock.NewRows([]string{"day", "count"}).
+			AddRow(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 1).
+			AddRow(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), 2),
+		)
+
+	stats, err := store.Stats(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	require.Len(t, stats.Daily, 2)
+	assert.Equal(t, int64(2), stats.Daily[1].Count)
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	m := migrations.NewMigrator(nil)
+
+	require.NoError(t, RegisterMigrations(m))
+	require.ErrorIs(t, RegisterMigrations(m), migrations.ErrDuplicateVersion)
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// MemoryStore in memory click storage
+type MemoryStore struct {
+	mu     sync.RWMutex
+	clicks map[string][]*domain.Click
+}
+
+// NewMemoryStore Factory
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{clicks: make(map[string][]*domain.Click)}
+}
+
+// AddClicks save collection of clicks
+func (m *MemoryStore) AddClicks(_ context.Context, clicks []*domain.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range clicks {
+		m.clicks[v.Alias] = append(m.clicks[v.Alias], v)
+	}
+
+	return nil
+}
+
+// DeleteClicks remove clicks of aliases
+func (m *MemoryStore) DeleteClicks(_ context.Context, aliases []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, alias := range aliases {
+		delete(m.clicks, alias)
+	}
+
+	return nil
+}
+
+// Stats return total and per day UTC clicks of alias
+func (m *MemoryStore) Stats(_ context.Context, alias string) (*domain.ClickStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := &domain.ClickStats{}
+	days := make(map[time.Time]int)
+
+	for _, v := range m.clicks[alias] {
+		at := v.At.UTC()
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+
+		i, ok := days[day]
+		if !ok {
+			i = len(stats.Daily)
+			days[day] = i
+			stats.Daily = append(stats.Daily, domain.DailyClicks{Day: day})
+		}
+
+		stats.Daily[i].Count++
+		stats.Total++
+	}
+
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Day.Before(stats.Daily[j].Day)
+	})
+
+	return stats, nil
+}
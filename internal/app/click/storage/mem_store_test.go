@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+func TestMemoryStore_Stats(t *testing.T) {
+	store := NewMemoryStore()
+	day := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+
+	err := store.AddClicks(context.Background(), []*domain.Click{
+		{Alias: "7A2S4z", At: day},
+		{Alias: "7A2S4z", At: day.Add(-24 * time.Hour)},
+		{Alias: "7A2S4z", At: day.Add(time.Hour)},
+		{Alias: "7A1S4z", At: day},
+	})
+	require.NoError(t, err)
+
+	stats, err := store.Stats(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Equal(t, []domain.DailyClicks{
+		{Day: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Count: 1},
+		{Day: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Count: 2},
+	}, stats.Daily)
+
+	stats, err = store.Stats(context.Background(), "none")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Total)
+
+	require.NoError(t, store.DeleteClicks(context.Background(), []string{"7A2S4z"}))
+
+	stats, err = store.Stats(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Total)
+
+	stats, err = store.Stats(context.Background(), "7A1S4z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Total)
+}
@@ -0,0 +1,13 @@
+create table if not exists click
+(
+    id         bigserial
+        constraint click_pk
+            primary key,
+    alias      varchar(32)              not null,
+    clicked_at timestamp with time zone not null,
+    referrer   text                     not null default '',
+    user_agent text                     not null default '',
+    ip         varchar(45)              not null default ''
+);
+
+create index if not exists click_idx_alias_clicked_at on click (alias, clicked_at);
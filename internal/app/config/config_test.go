@@ -1,11 +1,14 @@
 package config
 
 import (
+	"encoding/json"
 	"flag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfig(t *testing.T) {
@@ -112,3 +115,76 @@ func TestNewConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestNewConfig_File(t *testing.T) {
+	os.Clearenv()
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"server_address": ":7070",
+		"base_url": "http://file.ru",
+		"grpc_address": ":3300",
+		"jwt_keys": "k:HS256:file-secret",
+		"cache_size": 100,
+		"jwt_expire": "24h",
+		"delete_retention": "168h",
+		"purge_interval": 60000000000
+	}`), 0600))
+
+	t.Setenv("CACHE_SIZE", "5")
+
+	// explicit flag overrides file, defaults of flags don't
+	os.Args = []string{"cmd", "-c", file, "-b", "http://flag.ru"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	cfg, err := NewConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, ":7070", cfg.Listen)
+	assert.Equal(t, "http://flag.ru", cfg.BaseShortURL)
+	assert.Equal(t, ":3300", cfg.GRPCListen)
+	assert.Equal(t, "k:HS256:file-secret", cfg.GetJWTKeys())
+	assert.Equal(t, 5, cfg.GetCacheSize())
+	assert.Equal(t, 24*time.Hour, cfg.GetJWTExpire())
+	assert.Equal(t, 168*time.Hour, cfg.GetDeleteRetention())
+	assert.Equal(t, time.Minute, cfg.GetPurgeInterval())
+	// defaults of flags not set in file are kept
+	assert.Equal(t, time.Minute, cfg.GetCacheTTL())
+}
+
+func TestApp_UnmarshalJSON(t *testing.T) {
+	cfg := &App{CacheTTL: time.Minute}
+
+	require.NoError(t, json.Unmarshal([]byte(`{"reap_interval": "1m30s", "server_address": ":7070"}`), cfg))
+	assert.Equal(t, 90*time.Second, cfg.GetReapInterval())
+	assert.Equal(t, ":7070", cfg.Listen)
+	assert.Equal(t, time.Minute, cfg.GetCacheTTL())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"cache_ttl": "day"}`), cfg))
+	assert.Error(t, json.Unmarshal([]byte(`{"cache_ttl": true}`), cfg))
+}
+
+func TestNewConfig_Defaults(t *testing.T) {
+	os.Clearenv()
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	os.Args = []string{"cmd"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	cfg, err := NewConfig()
+	require.NoError(t, err)
+
+	// signing secret is not shared by deployments, listeners and irreversible purge are opt-in
+	assert.Empty(t, cfg.GetJWTKeys())
+	assert.Empty(t, cfg.GRPCListen)
+	assert.Zero(t, cfg.GetDeleteRetention())
+}
@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 // Config Database configuration
@@ -12,6 +15,15 @@ type Config interface {
 	GetDsn() string
 }
 
+// PoolConfig postgresql connections pool configuration
+type PoolConfig interface {
+	Config
+	GetDBMaxConns() int32
+	GetDBMinConns() int32
+	GetDBMaxConnIdleTime() time.Duration
+	GetDBMaxConnLifetime() time.Duration
+}
+
 // NewPgDB postgresql connection factory
 func NewPgDB(cfg Config) (*sql.DB, error) {
 	db, err := sql.Open("pgx", cfg.GetDsn())
@@ -25,3 +37,39 @@ func NewPgDB(cfg Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// NewPgPool postgresql connections pool factory, connections are opened on demand.
+// Every statement is prepared once per connection and its description is cached,
+// so repeated queries skip parsing and planning
+func NewPgPool(ctx context.Context, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.GetDsn())
+	if err != nil {
+		return nil, err
+	}
+
+	poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+
+	if cfg.GetDBMaxConns() > 0 {
+		poolCfg.MaxConns = cfg.GetDBMaxConns()
+	}
+
+	if cfg.GetDBMinConns() > 0 {
+		poolCfg.MinConns = cfg.GetDBMinConns()
+	}
+
+	if cfg.GetDBMaxConnIdleTime() > 0 {
+		poolCfg.MaxConnIdleTime = cfg.GetDBMaxConnIdleTime()
+	}
+
+	if cfg.GetDBMaxConnLifetime() > 0 {
+		poolCfg.MaxConnLifetime = cfg.GetDBMaxConnLifetime()
+	}
+
+	return pgxpool.NewWithConfig(ctx, poolCfg)
+}
+
+// SQLFromPool database/sql handle sharing connections of pool,
+// used by stores and migrations written for database/sql
+func SQLFromPool(pool *pgxpool.Pool) *sql.DB {
+	return stdlib.OpenDBFromPool(pool)
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteScheme DSN prefix to select sqlite database, example: sqlite:///var/lib/shortener/db.sqlite
+const SQLiteScheme = "sqlite://"
+
+// IsSQLite check DSN points to sqlite database
+func IsSQLite(dsn string) bool {
+	return strings.HasPrefix(dsn, SQLiteScheme)
+}
+
+// NewSQLiteDB sqlite connection factory
+func NewSQLiteDB(cfg Config) (*sql.DB, error) {
+	p := strings.TrimPrefix(cfg.GetDsn(), SQLiteScheme)
+	if p == "" {
+		return nil, fmt.Errorf("empty sqlite database path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0770); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000", p))
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite allows only one writer, single connection avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
@@ -0,0 +1,110 @@
+// Package storage persistent outbox of delete jobs
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// DBStorage postgresql delete outbox
+type DBStorage struct {
+	db *sql.DB
+}
+
+// NewStorage - delete outbox factory, schema must be created by RegisterMigrations
+func NewStorage(db *sql.DB) (*DBStorage, error) {
+	return &DBStorage{db: db}, nil
+}
+
+// RegisterMigrations register delete outbox schema migrations
+func RegisterMigrations(m *migrations.Migrator) error {
+	return m.Register(migrationFS, "migrations")
+}
+
+// Push save job to outbox
+func (d *DBStorage) Push(ctx context.Context, job *domain.DeleteJob) error {
+	aliases, err := json.Marshal(aliasesOf(job))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.ExecContext(
+		ctx,
+		"INSERT INTO delete_outbox (id, user_id, aliases, created_at) VALUES ($1, $2, $3, $4);",
+		job.ID, job.UserID, string(aliases), job.CreatedAt,
+	)
+
+	return err
+}
+
+// Pending return not acknowledged jobs in order of creation
+func (d *DBStorage) Pending(ctx context.Context) ([]*domain.DeleteJob, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT id, user_id, aliases, created_at FROM delete_outbox ORDER BY created_at;")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var jobs []*domain.DeleteJob
+	for rows.Next() {
+		var (
+			raw     string
+			aliases []string
+			job     = &domain.DeleteJob{}
+		)
+
+		if err = rows.Scan(&job.ID, &job.UserID, &raw, &job.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal([]byte(raw), &aliases); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, newJob(job, aliases))
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return jobs, nil
+}
+
+// Ack remove processed job from outbox
+func (d *DBStorage) Ack(ctx context.Context, id string) error {
+	_, err := d.db.ExecContext(ctx, "DELETE FROM delete_outbox WHERE id = $1;", id)
+
+	return err
+}
+
+func aliasesOf(job *domain.DeleteJob) []string {
+	aliases := make([]string, 0, len(job.Aliases))
+	for _, v := range job.Aliases {
+		aliases = append(aliases, v.Alias)
+	}
+
+	return aliases
+}
+
+// newJob fill restored job with pending aliases
+func newJob(job *domain.DeleteJob, aliases []string) *domain.DeleteJob {
+	job.Status = domain.DeleteJobQueued
+	job.UpdatedAt = job.CreatedAt
+	job.Aliases = make([]*domain.AliasDelete, 0, len(aliases))
+
+	for _, v := range aliases {
+		job.Aliases = append(job.Aliases, &domain.AliasDelete{Alias: v, Status: domain.AliasDeletePending})
+	}
+
+	return job
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/migrations"
+)
+
+func TestDBStorage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	store, err := NewStorage(db)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	job := newTestJob("0f5a6b8e-5c1a-4c57-9df4-8f1c2a7f3c11", "a", "b")
+
+	mock.ExpectExec("INSERT INTO delete_outbox").
+		WithArgs(job.ID, int64(1), `["a","b"]`, job.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, store.Push(ctx, job))
+
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, user_id, aliases, created_at FROM delete_outbox").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "aliases", "created_at"}).
+			AddRow(job.ID, 1, `["a","b"]`, created),
+		)
+
+	jobs, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+	assert.Equal(t, created, jobs[0].CreatedAt)
+	require.Len(t, jobs[0].Aliases, 2)
+	assert.Equal(t, "b", jobs[0].Aliases[1].Alias)
+
+	mock.ExpectExec("DELETE FROM delete_outbox").
+		WithArgs(job.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, store.Ack(ctx, job.ID))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	m := migrations.NewMigrator(nil)
+
+	require.NoError(t, RegisterMigrations(m))
+	require.ErrorIs(t, RegisterMigrations(m), migrations.ErrDuplicateVersion)
+}
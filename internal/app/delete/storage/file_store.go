@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/util"
+)
+
+// record types of JSON-lines outbox file
+const (
+	recordPush = "push"
+	recordAck  = "ack"
+)
+
+// compactSize minimal size of outbox file compacted on ack
+const compactSize = 1 << 20
+
+type fileRecord struct {
+	Type      string     `json:"type"`
+	ID        string     `json:"id"`
+	UserID    int64      `json:"user_id,omitempty"`
+	Aliases   []string   `json:"aliases,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// FileStorage append-only file delete outbox, every push and ack is a line of file.
+// Acknowledged jobs are dropped from file on open, when outbox becomes empty
+// and when file grows twice over its compacted size
+type FileStorage struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	// pending not acknowledged push records in order of file
+	pending []*fileRecord
+	// size of file and size it is compacted at
+	size       int64
+	compactAt  int64
+	minCompact int64
+}
+
+// NewFileStorage - file delete outbox factory
+func NewFileStorage(path string) (*FileStorage, error) {
+	f := &FileStorage{path: path, minCompact: compactSize}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	if err := f.rewrite(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Push append job to outbox file
+func (f *FileStorage) Push(_ context.Context, job *domain.DeleteJob) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	createdAt := job.CreatedAt.UTC()
+	rec := &fileRecord{
+		Type:      recordPush,
+		ID:        job.ID,
+		UserID:    job.UserID,
+		Aliases:   aliasesOf(job),
+		CreatedAt: &createdAt,
+	}
+
+	if err := f.save(rec); err != nil {
+		return err
+	}
+
+	f.pending = append(f.pending, rec)
+
+	return nil
+}
+
+// Pending return not acknowledged jobs in order of push
+func (f *FileStorage) Pending(_ context.Context) ([]*domain.DeleteJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	jobs := make([]*domain.DeleteJob, 0, len(f.pending))
+	for _, v := range f.pending {
+		jobs = append(jobs, newJob(&domain.DeleteJob{ID: v.ID, UserID: v.UserID, CreatedAt: *v.CreatedAt}, v.Aliases))
+	}
+
+	return jobs, nil
+}
+
+// Ack append acknowledge record, file is truncated when no jobs left and compacted when it grows
+func (f *FileStorage) Ack(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending := f.pending[:0]
+	for _, v := range f.pending {
+		if v.ID != id {
+			pending = append(pending, v)
+		}
+	}
+
+	f.pending = pending
+
+	if len(f.pending) == 0 {
+		if err := f.file.Truncate(0); err != nil {
+			return err
+		}
+
+		f.size = 0
+
+		return f.file.Sync()
+	}
+
+	if err := f.save(&fileRecord{Type: recordAck, ID: id}); err != nil {
+		return err
+	}
+
+	// failed jobs stay pending, so outbox is never empty and acks pile up
+	if f.size < f.compactAt {
+		return nil
+	}
+
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	return f.rewrite()
+}
+
+// Close - close outbox file
+func (f *FileStorage) Close() error {
+	return f.file.Close()
+}
+
+// load read pending jobs from file
+func (f *FileStorage) load() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	acked := make(map[string]struct{})
+
+	// line length is not limited, push record holds all aliases of job
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := f.loadRecord(line, acked); err != nil {
+				return err
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	pending := f.pending[:0]
+	for _, v := range f.pending {
+		if _, ok := acked[v.ID]; !ok {
+			pending = append(pending, v)
+		}
+	}
+
+	f.pending = pending
+
+	return nil
+}
+
+// loadRecord add push record to pending and ack record to acked
+func (f *FileStorage) loadRecord(line []byte, acked map[string]struct{}) error {
+	v := &fileRecord{}
+	if err := json.Unmarshal(line, v); err != nil {
+		return err
+	}
+
+	switch v.Type {
+	case recordPush:
+		if v.CreatedAt == nil {
+			v.CreatedAt = &time.Time{}
+		}
+
+		f.pending = append(f.pending, v)
+	case recordAck:
+		acked[v.ID] = struct{}{}
+	default:
+		return fmt.Errorf("unknown record type: %s", v.Type)
+	}
+
+	return nil
+}
+
+// rewrite atomically replace file with pending jobs only
+func (f *FileStorage) rewrite() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0770); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, v := range f.pending {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	if err := util.WriteFileAtomic(f.path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	f.file = file
+	f.size = int64(buf.Len())
+	f.compactAt = max(f.minCompact, 2*f.size)
+
+	return nil
+}
+
+// save append record to file and flush it to disk
+func (f *FileStorage) save(rec *fileRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	n, err := f.file.Write(append(b, '\n'))
+	f.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	return f.file.Sync()
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+func newTestJob(id string, aliases ...string) *domain.DeleteJob {
+	return newJob(&domain.DeleteJob{ID: id, UserID: 1, CreatedAt: time.Now().UTC()}, aliases)
+}
+
+func TestFileStorage_Restart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.delete")
+	ctx := context.Background()
+
+	store, err := NewFileStorage(path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Push(ctx, newTestJob("1", "a", "b")))
+	require.NoError(t, store.Push(ctx, newTestJob("2", "c")))
+	require.NoError(t, store.Ack(ctx, "1"))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStorage(path)
+	require.NoError(t, err)
+
+	jobs, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "2", jobs[0].ID)
+	assert.Equal(t, int64(1), jobs[0].UserID)
+	assert.Equal(t, domain.DeleteJobQueued, jobs[0].Status)
+	require.Len(t, jobs[0].Aliases, 1)
+	assert.Equal(t, "c", jobs[0].Aliases[0].Alias)
+	assert.Equal(t, domain.AliasDeletePending, jobs[0].Aliases[0].Status)
+
+	// acknowledged job is dropped on open
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+	require.NoError(t, store.Ack(ctx, "2"))
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+	require.NoError(t, store.Close())
+}
+
+func TestFileStorage_LongRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.delete")
+	ctx := context.Background()
+
+	// push record is longer than default buffer of bufio.Scanner
+	aliases := make([]string, 10000)
+	for i := range aliases {
+		aliases[i] = fmt.Sprintf("alias-%d", i)
+	}
+
+	store, err := NewFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Push(ctx, newTestJob("1", aliases...)))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStorage(path)
+	require.NoError(t, err)
+
+	jobs, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Len(t, jobs[0].Aliases, len(aliases))
+	require.NoError(t, store.Close())
+}
+
+func TestFileStorage_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.delete")
+	ctx := context.Background()
+
+	store, err := NewFileStorage(path)
+	require.NoError(t, err)
+	store.minCompact = 1024
+	store.compactAt = 1024
+
+	// failed job is never acked, outbox is never empty
+	require.NoError(t, store.Push(ctx, newTestJob("failed", "a")))
+
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		require.NoError(t, store.Push(ctx, newTestJob(id, "b")))
+		require.NoError(t, store.Ack(ctx, id))
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), int64(1024))
+
+	require.NoError(t, store.Push(ctx, newTestJob("last", "c")))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStorage(path)
+	require.NoError(t, err)
+
+	jobs, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "failed", jobs[0].ID)
+	assert.Equal(t, "last", jobs[1].ID)
+	require.NoError(t, store.Close())
+}
+
+func TestFileStorage_UnknownRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.delete")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"drop","id":"1"}`+"\n"), 0666))
+
+	_, err := NewFileStorage(path)
+	require.Error(t, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	require.NoError(t, store.Push(ctx, newTestJob("1", "a")))
+	require.NoError(t, store.Push(ctx, newTestJob("2", "b")))
+	require.NoError(t, store.Ack(ctx, "1"))
+
+	jobs, err := store.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "2", jobs[0].ID)
+}
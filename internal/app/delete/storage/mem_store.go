@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// MemoryStore in memory delete outbox for in memory shortener storage, jobs are lost on restart
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs []*domain.DeleteJob
+}
+
+// NewMemoryStore Factory
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Push save job to outbox
+func (m *MemoryStore) Push(_ context.Context, job *domain.DeleteJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs = append(m.jobs, job.Clone())
+
+	return nil
+}
+
+// Pending return not acknowledged jobs in order of push
+func (m *MemoryStore) Pending(_ context.Context) ([]*domain.DeleteJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]*domain.DeleteJob, 0, len(m.jobs))
+	for _, v := range m.jobs {
+		jobs = append(jobs, v.Clone())
+	}
+
+	return jobs, nil
+}
+
+// Ack remove processed job from outbox
+func (m *MemoryStore) Ack(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := m.jobs[:0]
+	for _, v := range m.jobs {
+		if v.ID != id {
+			jobs = append(jobs, v)
+		}
+	}
+
+	m.jobs = jobs
+
+	return nil
+}
@@ -0,0 +1,11 @@
+create table if not exists delete_outbox
+(
+    id         uuid                     not null
+        constraint delete_outbox_pk
+            primary key,
+    user_id    bigint                   not null,
+    aliases    jsonb                    not null,
+    created_at timestamp with time zone not null
+);
+
+create index if not exists delete_outbox_idx_created_at on delete_outbox (created_at);
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Click redirect event of short URL
+type Click struct {
+	Alias     string
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+// DailyClicks count of clicks per day, Day is UTC midnight
+type DailyClicks struct {
+	Day   time.Time
+	Count int64
+}
+
+// ClickStats short URL usage statistic
+type ClickStats struct {
+	Total int64
+	Daily []DailyClicks
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// DeleteJobStatus status of asynchronous delete job
+type DeleteJobStatus string
+
+// delete job statuses
+const (
+	DeleteJobQueued  DeleteJobStatus = "queued"
+	DeleteJobRunning DeleteJobStatus = "running"
+	DeleteJobDone    DeleteJobStatus = "done"
+	DeleteJobFailed  DeleteJobStatus = "failed"
+)
+
+// AliasDeleteStatus outcome of delete of one alias
+type AliasDeleteStatus string
+
+// alias delete outcomes
+const (
+	AliasDeletePending  AliasDeleteStatus = "pending"
+	AliasDeleteDone     AliasDeleteStatus = "deleted"
+	AliasDeleteNotFound AliasDeleteStatus = "not_found"
+	AliasDeleteFailed   AliasDeleteStatus = "failed"
+)
+
+// AliasDelete outcome of delete of alias in job
+type AliasDelete struct {
+	Alias  string
+	Status AliasDeleteStatus
+}
+
+// DeleteJob asynchronous delete of user links
+type DeleteJob struct {
+	ID     string
+	UserID int64
+	Status DeleteJobStatus
+	// Aliases outcome per alias in order of request
+	Aliases []*AliasDelete
+	// Error last error of failed batch
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Finished check job will not change anymore
+func (j *DeleteJob) Finished() bool {
+	return j.Status == DeleteJobDone || j.Status == DeleteJobFailed
+}
+
+// Clone deep copy of job
+func (j *DeleteJob) Clone() *DeleteJob {
+	c := *j
+	c.Aliases = make([]*AliasDelete, 0, len(j.Aliases))
+
+	for _, v := range j.Aliases {
+		a := *v
+		c.Aliases = append(c.Aliases, &a)
+	}
+
+	return &c
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Cursor position in user links ordered by creation time, alias breaks ties
+type Cursor struct {
+	CreatedAt time.Time
+	Alias     string
+}
+
+// UserURLQuery page request of user links ordered by creation time
+type UserURLQuery struct {
+	// Limit max count of links in page
+	Limit int
+	// After cursor of last link of previous page, nil - first page
+	After *Cursor
+	// Desc newest links first
+	Desc bool
+	// Contains substring of original URL, empty - any URL
+	Contains string
+	// IncludeDeleted return deleted links too
+	IncludeDeleted bool
+}
+
+// UserURLPage page of user links
+type UserURLPage struct {
+	Items BatchURL
+	// Next cursor of next page, nil - last page
+	Next *Cursor
+}
+
+// CursorOf return cursor pointing at link
+func CursorOf(u *URL) *Cursor {
+	return &Cursor{CreatedAt: u.CreatedAt, Alias: u.Alias}
+}
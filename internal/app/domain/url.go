@@ -1,11 +1,50 @@
 package domain
 
+import "time"
+
 // URL Base struct for app domain
 type URL struct {
 	URL     string
 	Alias   string
 	Deleted bool
+	// ExpiresAt link expiration time, zero - link never expires
+	ExpiresAt time.Time
+	// CreatedAt link creation time, set by store
+	CreatedAt time.Time
+	// UpdatedAt time of last destination change, set by store
+	UpdatedAt time.Time
+}
+
+// Expired check link is expired at time now
+func (u *URL) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
 }
 
 // BatchURL Base collection struct for app domain
 type BatchURL []*URL
+
+// Revision previous destination of short URL, saved when destination is changed
+type Revision struct {
+	Alias string
+	// URL destination before change
+	URL string
+	// UserID user who changed destination
+	UserID    int64
+	ChangedAt time.Time
+}
+
+// BatchItemStatus outcome of save of one link of batch
+type BatchItemStatus string
+
+// batch item outcomes
+const (
+	BatchItemCreated  BatchItemStatus = "created"
+	BatchItemExisting BatchItemStatus = "existing"
+)
+
+// BatchResult outcome of save of link of batch
+type BatchResult struct {
+	// URL saved link, for existing status - link shortened before
+	URL    *URL
+	Status BatchItemStatus
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// TokenKey metadata key with user token, value format "Bearer <token>"
+const TokenKey = "authorization"
+
+// Auth gRPC interceptor, read user ID from metadata using JWT middleware logic
+type Auth struct {
+	jwt    *middleware.Jwt
+	logger *zap.Logger
+	// set methods issue new token when request has no valid one
+	set map[string]struct{}
+	// read methods require valid token
+	read map[string]struct{}
+}
+
+// NewAuth Factory
+func NewAuth(jwt *middleware.Jwt, logger *zap.Logger) *Auth {
+	return &Auth{
+		jwt:    jwt,
+		logger: logger,
+		set: map[string]struct{}{
+			pb.Shortener_Shorten_FullMethodName:      {},
+			pb.Shortener_ShortenBatch_FullMethodName: {},
+		},
+		read: map[string]struct{}{
+			pb.Shortener_ListUserURLs_FullMethodName:    {},
+			pb.Shortener_DeleteUserURLs_FullMethodName:  {},
+			pb.Shortener_UpdateUserURL_FullMethodName:   {},
+			pb.Shortener_GetDeleteJob_FullMethodName:    {},
+			pb.Shortener_RestoreUserURLs_FullMethodName: {},
+		},
+	}
+}
+
+// Unary returns interceptor that put user ID to request context
+func (a *Auth) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		_, isSet := a.set[info.FullMethod]
+		_, isRead := a.read[info.FullMethod]
+
+		if !isSet && !isRead {
+			return handler(ctx, req)
+		}
+
+		token := a.tokenFromMetadata(ctx)
+		if token != "" {
+			userID, err := a.jwt.UserIDFromToken(token)
+			if err == nil {
+				return handler(util.SetUserIDToCtx(ctx, userID), req)
+			}
+
+			if isRead {
+				a.logger.Error("token not valid", zap.String("method", info.FullMethod))
+				return nil, status.Error(codes.Unauthenticated, "token not valid")
+			}
+		}
+
+		if isRead {
+			return nil, status.Error(codes.Unauthenticated, "token not found")
+		}
+
+		user, token, err := a.jwt.NewUserToken(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "can't create user")
+		}
+
+		if err = grpc.SetHeader(ctx, metadata.Pairs(TokenKey, "Bearer "+token)); err != nil {
+			return nil, status.Error(codes.Internal, "can't set token")
+		}
+
+		return handler(util.SetUserIDToCtx(ctx, user.ID), req)
+	}
+}
+
+func (a *Auth) tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, v := range md.Get(TokenKey) {
+		if token := middleware.TokenFromBearer(strings.TrimSpace(v)); token != "" {
+			return token
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/logger"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// RealIPKey metadata key with client address set by trusted proxy
+const RealIPKey = "x-real-ip"
+
+// RateLimit gRPC interceptors limiting shorten methods by token buckets of client IP and user,
+// quotas and limiter are the same as of HTTP endpoints
+type RateLimit struct {
+	limiter ratelimit.Limiter
+	realIP  *middleware.RealIP
+	ip      ratelimit.Quota
+	user    ratelimit.Quota
+	logger  *zap.Logger
+	// limited methods
+	methods map[string]struct{}
+}
+
+// NewRateLimit Factory, quotas are read from config
+func NewRateLimit(
+	limiter ratelimit.Limiter,
+	cfg middleware.RateLimitConfig,
+	realIP *middleware.RealIP,
+	logger *zap.Logger,
+) (*RateLimit, error) {
+	ip, err := ratelimit.ParseQuota(cfg.GetRateLimitIP())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ratelimit.ParseQuota(cfg.GetRateLimitUser())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimit{
+		limiter: limiter,
+		realIP:  realIP,
+		ip:      ip,
+		user:    user,
+		logger:  logger,
+		methods: map[string]struct{}{
+			pb.Shortener_Shorten_FullMethodName:      {},
+			pb.Shortener_ShortenBatch_FullMethodName: {},
+		},
+	}, nil
+}
+
+// UnaryIP returns interceptor that limits calls per client IP, it runs before Auth to limit creation of users too
+func (l *RateLimit) UnaryIP() grpc.UnaryServerInterceptor {
+	return l.unary(l.ip, func(ctx context.Context, method string) (string, bool) {
+		return "ip:" + method + ":" + l.clientIP(ctx), true
+	})
+}
+
+// UnaryUser returns interceptor that limits calls per user set by Auth
+func (l *RateLimit) UnaryUser() grpc.UnaryServerInterceptor {
+	return l.unary(l.user, func(ctx context.Context, method string) (string, bool) {
+		userID, ok := util.ReadUserIDFromCtx(ctx)
+		if !ok {
+			return "", false
+		}
+
+		return "user:" + method + ":" + strconv.FormatInt(userID, 10), true
+	})
+}
+
+func (l *RateLimit) unary(
+	q ratelimit.Quota,
+	key func(ctx context.Context, method string) (string, bool),
+) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := l.methods[info.FullMethod]; !ok || !q.Enabled() {
+			return handler(ctx, req)
+		}
+
+		k, ok := key(ctx, info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		res, err := l.limiter.Allow(ctx, k, q)
+		if err != nil {
+			// broken limiter must not stop service
+			logger.FromContext(ctx, l.logger).Error("can't check rate limit", zap.Error(err))
+			return handler(ctx, req)
+		}
+
+		if !res.Allowed {
+			retry := strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10)
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retry))
+
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// clientIP address of peer or address from metadata set by trusted proxy
+func (l *RateLimit) clientIP(ctx context.Context) string {
+	var addr, header string
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(RealIPKey); len(v) > 0 {
+			header = v[0]
+		}
+	}
+
+	return l.realIP.Resolve(addr, header)
+}
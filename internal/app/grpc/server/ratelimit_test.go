@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+)
+
+func TestRateLimit(t *testing.T) {
+	realIP, err := middleware.NewRealIP("")
+	require.NoError(t, err)
+
+	_, err = NewRateLimit(ratelimit.NewMemory(), &config.App{RateLimitIP: "1"}, realIP, zap.L())
+	require.ErrorIs(t, err, ratelimit.ErrInvalidQuota)
+
+	l, err := NewRateLimit(ratelimit.NewMemory(), &config.App{RateLimitIP: "3/1m", RateLimitUser: "1/1m"}, realIP, zap.L())
+	require.NoError(t, err)
+
+	client := newClient(t, memory.NewMemStore(), l)
+
+	var header metadata.MD
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, header.Get(TokenKey)[0])
+
+	// user quota is exhausted
+	_, err = client.ShortenBatch(ctx, &pb.ShortenBatchRequest{
+		Items: []*pb.ShortenBatchRequestItem{{CorrelationId: "1", OriginalUrl: "http://www.ya1.ru"}},
+	}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	_, err = client.ShortenBatch(ctx, &pb.ShortenBatchRequest{
+		Items: []*pb.ShortenBatchRequestItem{{CorrelationId: "1", OriginalUrl: "http://www.ya2.ru"}},
+	}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, header.Get("retry-after"))
+
+	// IP quota is exhausted for new users, other methods are not limited
+	for i := 0; i < 2; i++ {
+		_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya3.ru"})
+		require.NoError(t, err)
+	}
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya3.ru"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+}
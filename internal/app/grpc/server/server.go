@@ -0,0 +1,313 @@
+// Package server gRPC implementation of shortener API
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// Server gRPC shortener service
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	store         handlers.Store
+	cfg           handlers.Config
+	pingable      handlers.Pingable
+	deleteHandler *handlers.Delete
+	gen           alias.Generator
+}
+
+// NewServer Factory
+func NewServer(
+	store handlers.Store,
+	cfg handlers.Config,
+	p handlers.Pingable,
+	deleteHandler *handlers.Delete,
+	gen alias.Generator,
+) *Server {
+	return &Server{
+		store:         store,
+		cfg:           cfg,
+		pingable:      p,
+		deleteHandler: deleteHandler,
+		gen:           gen,
+	}
+}
+
+// Shorten create short URL for one URL
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	expiresAt, err := handlers.ParseExpiry(timestamp(req.GetExpiresAt()), req.GetTtl(), time.Now())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ent, err := handlers.ShortenURL(
+		ctx,
+		s.store,
+		s.gen,
+		&domain.URL{URL: req.GetUrl(), Alias: req.GetAlias(), ExpiresAt: expiresAt},
+		&domain.User{ID: userID},
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrIssetURL) {
+			return &pb.ShortenResponse{Result: s.shortLink(ent.Alias), Existing: true}, nil
+		}
+
+		if err = shortenError(err); err != nil {
+			return nil, err
+		}
+
+		return nil, status.Error(codes.Internal, "can't save url")
+	}
+
+	return &pb.ShortenResponse{Result: s.shortLink(ent.Alias)}, nil
+}
+
+// ShortenBatch create short URL for a collection of URL
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	if len(req.GetItems()) < 1 {
+		return nil, status.Error(codes.InvalidArgument, "empty batch")
+	}
+
+	now := time.Now()
+	batchReq := make(domain.BatchURL, 0, len(req.GetItems()))
+
+	for _, v := range req.GetItems() {
+		expiresAt, err := handlers.ParseExpiry(timestamp(v.GetExpiresAt()), v.GetTtl(), now)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		batchReq = append(batchReq, &domain.URL{URL: v.GetOriginalUrl(), Alias: v.GetCustomAlias(), ExpiresAt: expiresAt})
+	}
+
+	batchD, err := handlers.ShortenURLBatch(ctx, s.store, s.gen, batchReq, &domain.User{ID: userID})
+	if err != nil {
+		if err = shortenError(err); err != nil {
+			return nil, err
+		}
+
+		return nil, status.Error(codes.Internal, "can't save batch")
+	}
+
+	resp := &pb.ShortenBatchResponse{Items: make([]*pb.ShortenBatchResponseItem, 0, len(req.GetItems()))}
+	for i, v := range req.GetItems() {
+		resp.Items = append(resp.Items, &pb.ShortenBatchResponseItem{
+			CorrelationId: v.GetCorrelationId(),
+			ShortUrl:      s.shortLink(batchD[i].URL.Alias),
+			Existing:      batchD[i].Status == domain.BatchItemExisting,
+		})
+	}
+
+	return resp, nil
+}
+
+// Resolve return original URL by alias
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	if req.GetAlias() == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty alias")
+	}
+
+	ent, err := s.store.Read(ctx, req.GetAlias())
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "alias not found")
+		}
+
+		return nil, status.Error(codes.Internal, "can't read alias")
+	}
+
+	if ent.Deleted {
+		return nil, status.Error(codes.FailedPrecondition, "url deleted")
+	}
+
+	if ent.Expired(time.Now()) {
+		return nil, status.Error(codes.FailedPrecondition, "url expired")
+	}
+
+	return &pb.ResolveResponse{OriginalUrl: ent.URL}, nil
+}
+
+// ListUserURLs return page of user shorten URL ordered by creation time
+func (s *Server) ListUserURLs(ctx context.Context, req *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	q, err := handlers.NewUserURLQuery(
+		int(req.GetLimit()), req.GetCursor(), req.GetDesc(), req.GetContains(), req.GetIncludeDeleted(),
+	)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	page, err := s.store.ReadUserURLPage(ctx, &domain.User{ID: userID}, q)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can't read user urls")
+	}
+
+	resp := &pb.ListUserURLsResponse{Urls: make([]*pb.UserURL, 0, len(page.Items))}
+	for _, u := range page.Items {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    s.shortLink(u.Alias),
+			OriginalUrl: u.URL,
+			Deleted:     u.Deleted,
+		})
+	}
+
+	if page.Next != nil {
+		resp.NextCursor = handlers.EncodeCursor(page.Next)
+	}
+
+	return resp, nil
+}
+
+// DeleteUserURLs schedule delete of user shorten URL
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	job, err := s.deleteHandler.Enqueue(ctx, req.GetAliases(), userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can't enqueue delete")
+	}
+
+	return &pb.DeleteUserURLsResponse{JobId: job.ID}, nil
+}
+
+// GetDeleteJob return status of user delete job
+func (s *Server) GetDeleteJob(ctx context.Context, req *pb.GetDeleteJobRequest) (*pb.GetDeleteJobResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	job, ok := s.deleteHandler.ReadJob(req.GetId(), userID)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "job not found")
+	}
+
+	resp := &pb.GetDeleteJobResponse{
+		Id:        job.ID,
+		Status:    string(job.Status),
+		Aliases:   make([]*pb.AliasDelete, 0, len(job.Aliases)),
+		Error:     job.Error,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+
+	for _, v := range job.Aliases {
+		resp.Aliases = append(resp.Aliases, &pb.AliasDelete{Alias: v.Alias, Status: string(v.Status)})
+	}
+
+	return resp, nil
+}
+
+// RestoreUserURLs un-delete user shorten URL deleted within retention
+func (s *Server) RestoreUserURLs(ctx context.Context, req *pb.RestoreUserURLsRequest) (*pb.RestoreUserURLsResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	restored, err := handlers.RestoreURLs(ctx, s.store, req.GetAliases(), userID, s.cfg.GetDeleteRetention())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "can't restore urls")
+	}
+
+	return &pb.RestoreUserURLsResponse{Restored: restored}, nil
+}
+
+// UpdateUserURL change destination of user shorten URL
+func (s *Server) UpdateUserURL(ctx context.Context, req *pb.UpdateUserURLRequest) (*pb.UpdateUserURLResponse, error) {
+	userID, ok := util.ReadUserIDFromCtx(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "user not found")
+	}
+
+	ent, err := handlers.UpdateURL(ctx, s.store, req.GetAlias(), req.GetUrl(), &domain.User{ID: userID})
+	if err != nil {
+		switch {
+		case errors.Is(err, handlers.ErrInvalidURL):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, storage.ErrNotFound):
+			return nil, status.Error(codes.NotFound, "url not found")
+		case errors.Is(err, storage.ErrIssetURL):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, "can't update url")
+		}
+	}
+
+	return &pb.UpdateUserURLResponse{Url: &pb.UserURL{
+		ShortUrl:    s.shortLink(ent.Alias),
+		OriginalUrl: ent.URL,
+	}}, nil
+}
+
+// Ping check service status
+func (s *Server) Ping(_ context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.pingable.Ping(); err != nil {
+		return nil, status.Error(codes.Unavailable, "storage unavailable")
+	}
+
+	return &pb.PingResponse{}, nil
+}
+
+// shortenError map shorten errors caused by request, nil for internal errors
+func shortenError(err error) error {
+	var urlErr *url.Error
+
+	switch {
+	case errors.As(err, &urlErr):
+		return status.Error(codes.InvalidArgument, "invalid url")
+	case alias.IsInvalid(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrAliasTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return nil
+	}
+}
+
+// timestamp convert optional protobuf timestamp
+func timestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+
+	t := ts.AsTime()
+
+	return &t
+}
+
+func (s *Server) shortLink(alias string) string {
+	return fmt.Sprintf("%s/%s", s.cfg.GetBaseShortURL(), alias)
+}
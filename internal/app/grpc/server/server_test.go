@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/config"
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
+	"github.com/korol8484/shortener/internal/app/domain"
+	pb "github.com/korol8484/shortener/internal/app/grpc/proto"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+// newClient start server on buffer listener, rate limit interceptors are chained around Auth when given
+func newClient(t *testing.T, store handlers.Store, limit ...*RateLimit) pb.ShortenerClient {
+	dh, err := handlers.NewDelete(store, deleteStorage.NewMemoryStore(), zap.L())
+	require.NoError(t, err)
+
+	jwt := middleware.NewJwt(storage.NewMemoryStore(), zap.L(), "123")
+
+	interceptors := []grpc.UnaryServerInterceptor{NewAuth(jwt, zap.L()).Unary()}
+	for _, l := range limit {
+		interceptors = append([]grpc.UnaryServerInterceptor{l.UnaryIP()}, append(interceptors, l.UnaryUser())...)
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	pb.RegisterShortenerServer(s, NewServer(store, &config.App{BaseShortURL: "http://localhost"}, handlers.NewPingDummy(), dh, alias.NewHash(alias.DefaultLength)))
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		s.Stop()
+		dh.Close()
+	})
+
+	return pb.NewShortenerClient(conn)
+}
+
+func TestServer_Shorten(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	var header metadata.MD
+	resp, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://localhost/"+alias.FromHash(alias.DefaultLength, "http://www.ya.ru"), resp.GetResult())
+	assert.False(t, resp.GetExisting())
+	require.Len(t, header.Get(TokenKey), 1)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, header.Get(TokenKey)[0])
+
+	resp, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "http://www.ya.ru"})
+	require.NoError(t, err)
+	assert.True(t, resp.GetExisting())
+
+	list, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetUrls(), 1)
+	assert.Equal(t, "http://www.ya.ru", list.GetUrls()[0].GetOriginalUrl())
+}
+
+func TestServer_Shorten_CustomAlias(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	resp, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru", Alias: "spring-sale"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/spring-sale", resp.GetResult())
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya1.ru", Alias: "spring-sale"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya1.ru", Alias: "api"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_ShortenBatch(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	resp, err := client.ShortenBatch(context.Background(), &pb.ShortenBatchRequest{
+		Items: []*pb.ShortenBatchRequestItem{
+			{CorrelationId: "1", OriginalUrl: "http://www.ya.ru"},
+			{CorrelationId: "2", OriginalUrl: "http://www.ya1.ru"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetItems(), 2)
+	assert.Equal(t, "2", resp.GetItems()[1].GetCorrelationId())
+	assert.False(t, resp.GetItems()[1].GetExisting())
+
+	again, err := client.ShortenBatch(context.Background(), &pb.ShortenBatchRequest{
+		Items: []*pb.ShortenBatchRequestItem{{CorrelationId: "3", OriginalUrl: "http://www.ya.ru"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, again.GetItems()[0].GetExisting())
+	assert.Equal(t, resp.GetItems()[0].GetShortUrl(), again.GetItems()[0].GetShortUrl())
+
+	_, err = client.ShortenBatch(context.Background(), &pb.ShortenBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Resolve(t *testing.T) {
+	store := memory.NewMemStore()
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	client := newClient(t, store)
+
+	resp, err := client.Resolve(context.Background(), &pb.ResolveRequest{Alias: "7A2S4z"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya.ru", resp.GetOriginalUrl())
+
+	_, err = client.Resolve(context.Background(), &pb.ResolveRequest{Alias: "none"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{
+		Url:       "http://www.ya1.ru",
+		Alias:     "expired",
+		ExpiresAt: timestamppb.New(time.Now().Add(50 * time.Millisecond)),
+	})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, rErr := client.Resolve(context.Background(), &pb.ResolveRequest{Alias: "expired"})
+		return status.Code(rErr) == codes.FailedPrecondition
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya2.ru", Ttl: "-1h"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Unauthenticated(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	_, err := client.ListUserURLs(context.Background(), &pb.ListUserURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, "Bearer asdf")
+	_, err = client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{Aliases: []string{"7A2S4z"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_DeleteUserURLs(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	var header metadata.MD
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, header.Get(TokenKey)[0])
+	resp, err := client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{Aliases: []string{"7A2S4z"}})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetJobId())
+
+	require.Eventually(t, func() bool {
+		job, jErr := client.GetDeleteJob(ctx, &pb.GetDeleteJobRequest{Id: resp.GetJobId()})
+		require.NoError(t, jErr)
+
+		return job.GetStatus() == "done"
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = client.GetDeleteJob(ctx, &pb.GetDeleteJobRequest{Id: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_RestoreUserURLs(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	var header metadata.MD
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru", Alias: "sale"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, header.Get(TokenKey)[0])
+
+	resp, err := client.DeleteUserURLs(ctx, &pb.DeleteUserURLsRequest{Aliases: []string{"sale"}})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, jErr := client.GetDeleteJob(ctx, &pb.GetDeleteJobRequest{Id: resp.GetJobId()})
+		require.NoError(t, jErr)
+
+		return job.GetStatus() == "done"
+	}, time.Second, 10*time.Millisecond)
+
+	restored, err := client.RestoreUserURLs(ctx, &pb.RestoreUserURLsRequest{Aliases: []string{"sale", "unknown"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sale"}, restored.GetRestored())
+
+	_, err = client.RestoreUserURLs(context.Background(), &pb.RestoreUserURLsRequest{Aliases: []string{"sale"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_UpdateUserURL(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	var header metadata.MD
+	_, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "http://www.ya.ru", Alias: "sale"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), TokenKey, header.Get(TokenKey)[0])
+
+	resp, err := client.UpdateUserURL(ctx, &pb.UpdateUserURLRequest{Alias: "sale", Url: "http://www.ya.ru/new"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya.ru/new", resp.GetUrl().GetOriginalUrl())
+
+	_, err = client.UpdateUserURL(ctx, &pb.UpdateUserURLRequest{Alias: "other", Url: "http://www.ya.ru/new"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.UpdateUserURL(ctx, &pb.UpdateUserURLRequest{Alias: "sale"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Ping(t *testing.T) {
+	client := newClient(t, memory.NewMemStore())
+
+	_, err := client.Ping(context.Background(), &pb.PingRequest{})
+	require.NoError(t, err)
+}
@@ -2,40 +2,52 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
 	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
 type batchRequestItem struct {
-	ID  string `json:"correlation_id"`
-	URL string `json:"original_url"`
+	ID        string     `json:"correlation_id"`
+	URL       string     `json:"original_url"`
+	Alias     string     `json:"custom_alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
 }
 
 type batchResponseItem struct {
-	ID  string `json:"correlation_id"`
-	URL string `json:"short_url"`
+	ID     string                 `json:"correlation_id"`
+	URL    string                 `json:"short_url"`
+	Status domain.BatchItemStatus `json:"status"`
 }
 
 type batchRequest []batchRequestItem
 type batchResponse []batchResponseItem
 
-// ShortenBatch Handler for a collection of shortened links
+// ShortenBatch Handler for a collection of shortened links, custom_alias, expires_at and ttl are optional
 // Accepts input json:
 //
 //	[{
 //	    "correlation_id": "id",
-//	    "original_url": "http://www.ya.ru"
+//	    "original_url": "http://www.ya.ru",
+//	    "custom_alias": "spring-sale",
+//	    "expires_at": "2030-01-01T00:00:00Z"
 //	}]
 //
-// Returns:
+// Returns 201 when at least one URL is shortened and 409 when every URL was shortened before,
+// status of item is "created" or "existing" with short URL saved before:
 //
 //	[{
 //	    "correlation_id": "id",
-//	    "short_url": "http://localhost:8080/ZyNJrg"
+//	    "short_url": "http://localhost:8080/ZyNJrg",
+//	    "status": "created"
 //	}]
 func (a *API) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
@@ -55,34 +67,56 @@ func (a *API) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	batchD := make(domain.BatchURL, 0, len(req))
-	batchR := make(batchResponse, 0, len(req))
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	batchReq := make(domain.BatchURL, 0, len(req))
 
 	for _, v := range req {
-		var ent *domain.URL
+		var expiresAt time.Time
 
-		ent, err = a.shortURL(v.URL)
+		expiresAt, err = ParseExpiry(v.ExpiresAt, v.TTL, now)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		batchD = append(batchD, ent)
-		batchR = append(batchR, batchResponseItem{
-			ID:  v.ID,
-			URL: fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), ent.Alias),
-		})
+		batchReq = append(batchReq, &domain.URL{URL: v.URL, Alias: v.Alias, ExpiresAt: expiresAt})
 	}
 
-	userID, ok := util.ReadUserIDFromCtx(r.Context())
-	if !ok {
+	batchD, err := ShortenURLBatch(r.Context(), a.store, a.gen, batchReq, &domain.User{ID: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrAliasTaken) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+
+		if alias.IsInvalid(err) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if err = a.store.AddBatch(r.Context(), batchD, &domain.User{ID: userID}); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	code := http.StatusConflict
+	batchR := make(batchResponse, 0, len(req))
+
+	for i, v := range req {
+		if batchD[i].Status == domain.BatchItemCreated {
+			code = http.StatusCreated
+		}
+
+		batchR = append(batchR, batchResponseItem{
+			ID:     v.ID,
+			URL:    fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), batchD[i].URL.Alias),
+			Status: batchD[i].Status,
+		})
 	}
 
 	b, err := json.Marshal(batchR)
@@ -92,6 +126,6 @@ func (a *API) ShortenBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("content-type", mimeJSON)
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(code)
 	_, _ = w.Write(b)
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/config"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
@@ -31,7 +32,7 @@ func TestAPI_ShortenBatch(t *testing.T) {
 		_ = store.Close()
 	}(store)
 
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
 	router.Post("/batch", api.ShortenBatch)
 
 	client := &http.Client{}
@@ -53,6 +54,18 @@ func TestAPI_ShortenBatch(t *testing.T) {
 			contentType: "application/json",
 			body:        "[{\"correlation_id\":\"id\",\"original_url\":\"http://www.ya.ru\"}]",
 		}},
+		{name: "all_urls_existing", want: want{
+			method:      http.MethodPost,
+			code:        409,
+			contentType: "application/json",
+			body:        "[{\"correlation_id\":\"id\",\"original_url\":\"http://www.ya.ru\"}]",
+		}},
+		{name: "some_urls_existing", want: want{
+			method:      http.MethodPost,
+			code:        201,
+			contentType: "application/json",
+			body:        "[{\"correlation_id\":\"1\",\"original_url\":\"http://www.ya.ru\"},{\"correlation_id\":\"2\",\"original_url\":\"http://www.ya3.ru\"}]",
+		}},
 		{name: "not_post_request", want: want{
 			method: http.MethodGet,
 			code:   405,
@@ -62,6 +75,24 @@ func TestAPI_ShortenBatch(t *testing.T) {
 			code:   400,
 			body:   "[{\"correlation_id\":\"id\",\"original_url\":\"http___://www.ya.ru\"}]",
 		}},
+		{name: "success_custom_alias", want: want{
+			method:      http.MethodPost,
+			code:        201,
+			contentType: "application/json",
+			body:        "[{\"correlation_id\":\"id\",\"original_url\":\"http://www.ya1.ru\",\"custom_alias\":\"spring-sale\"}]",
+		}},
+		{name: "custom_alias_taken", want: want{
+			method:      http.MethodPost,
+			code:        409,
+			contentType: "application/json",
+			body:        "[{\"correlation_id\":\"id\",\"original_url\":\"http://www.ya2.ru\",\"custom_alias\":\"spring-sale\"}]",
+		}},
+		{name: "custom_alias_invalid", want: want{
+			method:      http.MethodPost,
+			code:        400,
+			contentType: "application/json",
+			body:        "[{\"correlation_id\":\"id\",\"original_url\":\"http://www.ya2.ru\",\"custom_alias\":\"spring sale\"}]",
+		}},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// ClickStore click events repository
+type ClickStore interface {
+	AddClicks(ctx context.Context, clicks []*domain.Click) error
+	Stats(ctx context.Context, alias string) (*domain.ClickStats, error)
+	DeleteClicks(ctx context.Context, aliases []string) error
+}
+
+type dailyClicks struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+type statsResponse struct {
+	Alias string        `json:"alias"`
+	Total int64         `json:"total"`
+	Daily []dailyClicks `json:"daily"`
+}
+
+// Clicks records redirects of short URL with async buffered pipeline and serves statistic
+type Clicks struct {
+	store      ClickStore
+	urlStore   Store
+	clickChan  chan *domain.Click
+	closeChan  chan struct{}
+	doneChan   chan struct{}
+	logger     *zap.Logger
+	batchSize  int
+	flushEvery time.Duration
+}
+
+// NewClicks Factory, starts background worker
+func NewClicks(store ClickStore, urlStore Store, logger *zap.Logger) (*Clicks, error) {
+	c := &Clicks{
+		store:      store,
+		urlStore:   urlStore,
+		clickChan:  make(chan *domain.Click, 4096),
+		closeChan:  make(chan struct{}),
+		doneChan:   make(chan struct{}),
+		logger:     logger,
+		batchSize:  500,
+		flushEvery: time.Second,
+	}
+
+	go c.process()
+
+	return c, nil
+}
+
+// Track middleware for redirect handler, records click when handler redirects
+func (c *Clicks) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(sw, r)
+
+		if sw.status != http.StatusTemporaryRedirect {
+			return
+		}
+
+		c.add(&domain.Click{
+			Alias:     chi.URLParam(r, "id"),
+			At:        time.Now(),
+			Referrer:  r.Referer(),
+			UserAgent: r.UserAgent(),
+			IP:        middleware.ClientIP(r),
+		})
+	})
+}
+
+// Stats Handler for statistic of user shorten URL
+// Returns:
+//
+//	{
+//	    "alias": "ZyNJrg",
+//	    "total": 3,
+//	    "daily": [{"date": "2024-05-01", "count": 3}]
+//	}
+func (c *Clicks) Stats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	alias := chi.URLParam(r, "alias")
+
+	if _, err := c.urlStore.ReadUserAlias(r.Context(), alias, &domain.User{ID: userID}); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	stats, err := c.store.Stats(r.Context(), alias)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := &statsResponse{Alias: alias, Total: stats.Total, Daily: make([]dailyClicks, 0, len(stats.Daily))}
+	for _, v := range stats.Daily {
+		resp.Daily = append(resp.Daily, dailyClicks{Date: v.Day.Format(time.DateOnly), Count: v.Count})
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
+// Purged remove clicks of purged links, so alias taken again starts without clicks
+func (c *Clicks) Purged(ctx context.Context, aliases []string) error {
+	return c.store.DeleteClicks(ctx, aliases)
+}
+
+// Close - stop background worker, buffered clicks are saved
+func (c *Clicks) Close() {
+	close(c.closeChan)
+	<-c.doneChan
+}
+
+// add enqueue click, click is dropped when buffer is full to not slow down redirects
+func (c *Clicks) add(click *domain.Click) {
+	select {
+	case c.clickChan <- click:
+	default:
+		c.logger.Warn("click buffer is full, click dropped", zap.String("alias", click.Alias))
+	}
+}
+
+func (c *Clicks) process() {
+	defer close(c.doneChan)
+
+	ticker := time.NewTicker(c.flushEvery)
+	defer ticker.Stop()
+
+	batch := make([]*domain.Click, 0, c.batchSize)
+
+	for {
+		select {
+		case click := <-c.clickChan:
+			batch = append(batch, click)
+			if len(batch) >= c.batchSize {
+				batch = c.flush(batch)
+			}
+		case <-ticker.C:
+			batch = c.flush(batch)
+		case <-c.closeChan:
+			for {
+				select {
+				case click := <-c.clickChan:
+					batch = append(batch, click)
+				default:
+					c.flush(batch)
+					c.logger.Info("close click worker")
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Clicks) flush(batch []*domain.Click) []*domain.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := c.store.AddClicks(context.Background(), batch); err != nil {
+		c.logger.Error("can't save clicks", zap.Int("count", len(batch)), zap.Error(err))
+	}
+
+	return batch[:0]
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
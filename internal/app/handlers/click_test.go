@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	clickStorage "github.com/korol8484/shortener/internal/app/click/storage"
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+func TestClicks(t *testing.T) {
+	router := chi.NewRouter()
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	store := memory.NewMemStore()
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	clickStore := clickStorage.NewMemoryStore()
+
+	clicks, err := NewClicks(clickStore, store, zap.L())
+	require.NoError(t, err)
+
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
+	router.With(clicks.Track).Get("/{id}", api.HandleRedirect)
+	router.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), 1)))
+		})
+	}).Get("/stats/{alias}", clicks.Stats)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, a := range []string{"7A2S4z", "7A2S4z", "111111"} {
+		req, rErr := http.NewRequest(http.MethodGet, srv.URL+"/"+a, nil)
+		require.NoError(t, rErr)
+		req.Header.Set("Referer", "http://referrer.ru")
+
+		res, rErr := client.Do(req)
+		require.NoError(t, rErr)
+		_ = res.Body.Close()
+	}
+
+	// saved on close
+	clicks.Close()
+
+	stats, err := clickStore.Stats(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+
+	res, err := client.Get(srv.URL + "/stats/7A2S4z")
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	resp := &statsResponse{}
+	require.NoError(t, json.Unmarshal(body, resp))
+	assert.Equal(t, int64(2), resp.Total)
+	require.Len(t, resp.Daily, 1)
+	assert.Equal(t, time.Now().UTC().Format(time.DateOnly), resp.Daily[0].Date)
+
+	res2, err := client.Get(srv.URL + "/stats/111111")
+	require.NoError(t, err)
+
+	defer res2.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, res2.StatusCode)
+
+	// link of another user
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "8B3T5a"}, &domain.User{ID: 2})
+	require.NoError(t, err)
+
+	res3, err := client.Get(srv.URL + "/stats/8B3T5a")
+	require.NoError(t, err)
+
+	defer res3.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, res3.StatusCode)
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// Compactable storage with online compaction
+type Compactable interface {
+	Compact() error
+}
+
+// Compact admin HTTP handler, run storage compaction, must be served from trusted subnet
+func Compact(c Compactable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := c.Compact(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
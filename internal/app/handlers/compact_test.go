@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type compactFunc func() error
+
+func (f compactFunc) Compact() error {
+	return f()
+}
+
+func TestCompact(t *testing.T) {
+	w := httptest.NewRecorder()
+	Compact(compactFunc(func() error { return nil }))(w, httptest.NewRequest(http.MethodPost, "/api/admin/compact", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	Compact(compactFunc(func() error { return errors.New("error") }))(w, httptest.NewRequest(http.MethodPost, "/api/admin/compact", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -5,47 +5,150 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
+// deleteJobPath path of delete job status endpoint
+const deleteJobPath = "/api/user/urls/delete-jobs/"
+
+// tracerScope instrumentation scope of background spans
+const tracerScope = "github.com/korol8484/shortener/internal/app/handlers"
+
 type batchItem struct {
+	job     string
 	aliases []string
 	user    int64
+	// link span of request which enqueued job, invalid for replayed jobs
+	link trace.SpanContext
+}
+
+type aliasDeleteResponse struct {
+	Alias  string `json:"alias"`
+	Status string `json:"status"`
+}
+
+type deleteJobResponse struct {
+	ID        string                `json:"id"`
+	Status    string                `json:"status"`
+	Aliases   []aliasDeleteResponse `json:"aliases"`
+	Error     string                `json:"error,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// DeleteQueue persistent outbox of delete jobs, job stays in outbox until it is acknowledged
+type DeleteQueue interface {
+	Push(ctx context.Context, job *domain.DeleteJob) error
+	Pending(ctx context.Context) ([]*domain.DeleteJob, error)
+	Ack(ctx context.Context, id string) error
+}
+
+// DeleteStats queue depth and batch outcomes of delete workers
+type DeleteStats struct {
+	// Queued batches waiting for workers
+	Queued int
+	// Deleted batches processed by store
+	Deleted int64
+	// Failed batches not processed after all attempts
+	Failed int64
+	// Retried failed attempts which were retried
+	Retried int64
 }
 
 // Delete BatchDelete Handler
 type Delete struct {
-	store     Store
-	batchChan chan batchItem
+	store Store
+	queue DeleteQueue
+	jobs  *deleteJobs
+	mu    sync.Mutex
+	// batches not taken by workers yet
+	batches   []batchItem
+	notify    chan struct{}
 	closeChan chan struct{}
+	wg        sync.WaitGroup
 	logger    *zap.Logger
 	batchSize int
+	// attempts count of store calls per batch, retries wait backoff doubled up to maxBackoff
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	deleted    atomic.Int64
+	failed     atomic.Int64
+	retried    atomic.Int64
+}
+
+// NewDelete Factory for BatchDelete Handler, jobs left in queue by previous run are replayed
+func NewDelete(store Store, queue DeleteQueue, logger *zap.Logger) (*Delete, error) {
+	d := newDelete(store, queue, logger)
+
+	if err := d.start(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func newDelete(store Store, queue DeleteQueue, logger *zap.Logger) *Delete {
+	return &Delete{
+		store:      store,
+		queue:      queue,
+		jobs:       newDeleteJobs(deleteJobTTL),
+		notify:     make(chan struct{}, 1),
+		closeChan:  make(chan struct{}),
+		logger:     logger,
+		batchSize:  500,
+		attempts:   5,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+	}
 }
 
-// NewDelete Factory for BatchDelete Handler
-func NewDelete(store Store, logger *zap.Logger) (*Delete, error) {
-	d := &Delete{
-		store:     store,
-		batchChan: make(chan batchItem, 1024),
-		closeChan: make(chan struct{}),
-		logger:    logger,
-		batchSize: 500,
+// start replay queued jobs and run workers
+func (d *Delete) start() error {
+	pending, err := d.queue.Pending(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, job := range pending {
+		d.jobs.restore(job, d.batchCount(len(job.Aliases)))
+		d.schedule(job, trace.SpanContext{})
+	}
+
+	if len(pending) > 0 {
+		d.logger.Info("replay delete jobs", zap.Int("jobs", len(pending)))
 	}
 
 	for i := 0; i < 2; i++ {
+		d.wg.Add(1)
 		go d.process()
 	}
 
-	return d, nil
+	return nil
 }
 
 // BatchDelete Handler for a collection of delete user shorten URLs
 // Accepts input json:
 // ["cbi7jn", "dyifOs"]
-// Returns: Http status Accepted
+// Returns: Http status Accepted, delete job in body and job status URL in Location header
+//
+//	{
+//	    "id": "3f0c4b8e-...",
+//	    "status": "queued",
+//	    "aliases": [{"alias": "cbi7jn", "status": "pending"}]
+//	}
 func (d *Delete) BatchDelete(w http.ResponseWriter, r *http.Request) {
 	userID, ok := util.ReadUserIDFromCtx(r.Context())
 	if !ok {
@@ -66,49 +169,267 @@ func (d *Delete) BatchDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go d.add(aliases, userID)
+	job, err := d.Enqueue(r.Context(), aliases, userID)
+	if err != nil {
+		http.Error(w, "can't enqueue delete", http.StatusInternalServerError)
+		return
+	}
 
-	w.WriteHeader(http.StatusAccepted)
+	w.Header().Set("Location", deleteJobPath+job.ID)
+	writeDeleteJob(w, http.StatusAccepted, job)
 }
 
-// Close - close resources
+// Job Handler for status of user delete job, 404 for unknown job or job of another user
+func (d *Delete) Job(w http.ResponseWriter, r *http.Request) {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	job, ok := d.ReadJob(chi.URLParam(r, "id"), userID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeDeleteJob(w, http.StatusOK, job)
+}
+
+// Enqueue save job to queue and schedule aliases for asynchronous delete, returns queued job
+func (d *Delete) Enqueue(ctx context.Context, aliases []string, userID int64) (*domain.DeleteJob, error) {
+	aliases = unique(aliases)
+
+	job := d.jobs.create(aliases, userID, d.batchCount(len(aliases)))
+	if job.Finished() {
+		return job, nil
+	}
+
+	if err := d.queue.Push(ctx, job); err != nil {
+		d.jobs.remove(job.ID)
+		return nil, err
+	}
+
+	d.schedule(job, trace.SpanContextFromContext(ctx))
+
+	return job, nil
+}
+
+// ReadJob return delete job of user
+func (d *Delete) ReadJob(id string, userID int64) (*domain.DeleteJob, bool) {
+	return d.jobs.get(id, userID)
+}
+
+// Stats return queue depth and batch outcomes counted since start
+func (d *Delete) Stats() DeleteStats {
+	d.mu.Lock()
+	queued := len(d.batches)
+	d.mu.Unlock()
+
+	return DeleteStats{
+		Queued:  queued,
+		Deleted: d.deleted.Load(),
+		Failed:  d.failed.Load(),
+		Retried: d.retried.Load(),
+	}
+}
+
+// Close - stop workers after queued batches are processed,
+// batch failed while closing stays in queue till next start
 func (d *Delete) Close() {
 	close(d.closeChan)
-	close(d.batchChan)
+	d.wg.Wait()
 }
 
-func (d *Delete) add(aliases []string, userID int64) {
-	for i := 0; i < len(aliases); i += d.batchSize {
-		end := i + d.batchSize
-		if end > len(aliases) {
-			end = len(aliases)
-		}
+func (d *Delete) batchCount(aliases int) int {
+	return (aliases + d.batchSize - 1) / d.batchSize
+}
 
-		d.batchChan <- batchItem{
-			aliases: aliases[i:end],
-			user:    userID,
+// schedule split job to batches for workers, batches are linked to span of request
+func (d *Delete) schedule(job *domain.DeleteJob, link trace.SpanContext) {
+	d.mu.Lock()
+	for i := 0; i < len(job.Aliases); i += d.batchSize {
+		end := min(i+d.batchSize, len(job.Aliases))
+
+		aliases := make([]string, 0, end-i)
+		for _, v := range job.Aliases[i:end] {
+			aliases = append(aliases, v.Alias)
 		}
+
+		d.batches = append(d.batches, batchItem{
+			job:     job.ID,
+			aliases: aliases,
+			user:    job.UserID,
+			link:    link,
+		})
+	}
+	d.mu.Unlock()
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next take first scheduled batch
+func (d *Delete) next() (batchItem, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.batches) == 0 {
+		return batchItem{}, false
 	}
+
+	batch := d.batches[0]
+	d.batches = d.batches[1:]
+
+	return batch, true
 }
 
 func (d *Delete) process() {
+	defer d.wg.Done()
+
 	for {
+		if batch, ok := d.next(); ok {
+			d.handle(batch)
+			continue
+		}
+
 		select {
-		case batch, ok := <-d.batchChan:
-			if !ok {
-				return
+		case <-d.notify:
+		case <-d.closeChan:
+			// drain batches scheduled before close
+			for batch, ok := d.next(); ok; batch, ok = d.next() {
+				d.handle(batch)
 			}
 
-			if err := d.store.BatchDelete(context.Background(), batch.aliases, batch.user); err != nil {
-				d.logger.Error(
-					"can't delete bach",
-					zap.Int64("userId", batch.user),
-					zap.Error(err),
-				)
-			}
-		case <-d.closeChan:
 			d.logger.Info("close delete worker")
 			return
 		}
 	}
 }
+
+// handle delete batch with retries, job is removed from queue when its last batch is processed
+// and none of its batches failed.
+// Batch is traced in own root span linked to request which enqueued it
+func (d *Delete) handle(batch batchItem) {
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("shortener.delete_job", batch.job),
+			attribute.Int("shortener.batch_size", len(batch.aliases)),
+		),
+	}
+
+	if batch.link.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: batch.link}))
+	}
+
+	ctx, span := otel.Tracer(tracerScope).Start(context.Background(), "delete.batch", opts...)
+	defer span.End()
+
+	d.jobs.start(batch.job)
+
+	var (
+		deleted []string
+		err     error
+	)
+
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		deleted, err = d.store.BatchDelete(ctx, batch.aliases, batch.user)
+		if err == nil || attempt >= d.attempts {
+			break
+		}
+
+		d.retried.Add(1)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
+		d.logger.Warn(
+			"retry delete batch",
+			zap.String("job", batch.job),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-d.closeChan:
+			d.logger.Info("delete batch left in queue", zap.String("job", batch.job))
+			return
+		}
+
+		backoff = min(backoff*2, d.maxBackoff)
+	}
+
+	if err != nil {
+		d.failed.Add(1)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		d.logger.Error(
+			"can't delete batch",
+			zap.Int64("userId", batch.user),
+			zap.String("job", batch.job),
+			zap.Error(err),
+		)
+	} else {
+		d.deleted.Add(1)
+	}
+
+	status, finished := d.jobs.finish(batch.job, batch.aliases, deleted, err)
+	if !finished {
+		return
+	}
+
+	// failed job stays in queue and is replayed on next start
+	if status == domain.DeleteJobFailed {
+		d.logger.Warn("failed delete job left in queue", zap.String("job", batch.job))
+		return
+	}
+
+	if err = d.queue.Ack(ctx, batch.job); err != nil {
+		d.logger.Error("can't ack delete job", zap.String("job", batch.job), zap.Error(err))
+	}
+}
+
+func writeDeleteJob(w http.ResponseWriter, code int, job *domain.DeleteJob) {
+	resp := &deleteJobResponse{
+		ID:        job.ID,
+		Status:    string(job.Status),
+		Aliases:   make([]aliasDeleteResponse, 0, len(job.Aliases)),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+
+	for _, v := range job.Aliases {
+		resp.Aliases = append(resp.Aliases, aliasDeleteResponse{Alias: v.Alias, Status: string(v.Status)})
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
+
+// unique remove duplicates keeping order
+func unique(aliases []string) []string {
+	seen := make(map[string]struct{}, len(aliases))
+	res := make([]string, 0, len(aliases))
+
+	for _, v := range aliases {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+
+	return res
+}
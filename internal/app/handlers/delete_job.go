@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// deleteJobTTL finished jobs are kept for status requests during ttl
+const deleteJobTTL = time.Hour
+
+type jobState struct {
+	job *domain.DeleteJob
+	// pending count of batches not processed yet
+	pending int
+	// index position of alias in job aliases
+	index map[string]int
+}
+
+// deleteJobs in memory registry of delete jobs
+type deleteJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*jobState
+	ttl  time.Duration
+}
+
+func newDeleteJobs(ttl time.Duration) *deleteJobs {
+	return &deleteJobs{jobs: make(map[string]*jobState), ttl: ttl}
+}
+
+// create register queued job for aliases split into batches, job without batches is done at once
+func (j *deleteJobs) create(aliases []string, userID int64, batches int) *domain.DeleteJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.prune(now)
+
+	state := &jobState{
+		job: &domain.DeleteJob{
+			ID:        uuid.NewString(),
+			UserID:    userID,
+			Status:    domain.DeleteJobQueued,
+			Aliases:   make([]*domain.AliasDelete, 0, len(aliases)),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		pending: batches,
+		index:   make(map[string]int, len(aliases)),
+	}
+
+	for i, alias := range aliases {
+		state.index[alias] = i
+		state.job.Aliases = append(state.job.Aliases, &domain.AliasDelete{Alias: alias, Status: domain.AliasDeletePending})
+	}
+
+	if batches == 0 {
+		state.job.Status = domain.DeleteJobDone
+	}
+
+	j.jobs[state.job.ID] = state
+
+	return state.job.Clone()
+}
+
+// restore register job replayed from queue
+func (j *deleteJobs) restore(job *domain.DeleteJob, batches int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state := &jobState{job: job.Clone(), pending: batches, index: make(map[string]int, len(job.Aliases))}
+	for i, v := range job.Aliases {
+		state.index[v.Alias] = i
+	}
+
+	j.jobs[job.ID] = state
+}
+
+// remove forget job which was not queued
+func (j *deleteJobs) remove(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.jobs, id)
+}
+
+// start mark job running when worker takes its batch
+func (j *deleteJobs) start(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state, ok := j.jobs[id]
+	if !ok || state.job.Status != domain.DeleteJobQueued {
+		return
+	}
+
+	state.job.Status = domain.DeleteJobRunning
+	state.job.UpdatedAt = time.Now()
+}
+
+// finish save outcome of batch, job is finished with last batch and failed when any batch failed.
+// Returns status of job and true when job is finished
+func (j *deleteJobs) finish(id string, aliases []string, deleted []string, err error) (domain.DeleteJobStatus, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state, ok := j.jobs[id]
+	if !ok {
+		return "", false
+	}
+
+	status := domain.AliasDeleteNotFound
+	if err != nil {
+		status = domain.AliasDeleteFailed
+		state.job.Error = err.Error()
+	}
+
+	for _, alias := range aliases {
+		state.job.Aliases[state.index[alias]].Status = status
+	}
+
+	for _, alias := range deleted {
+		if i, ok := state.index[alias]; ok {
+			state.job.Aliases[i].Status = domain.AliasDeleteDone
+		}
+	}
+
+	state.pending--
+	state.job.UpdatedAt = time.Now()
+
+	if state.pending > 0 {
+		return state.job.Status, false
+	}
+
+	state.job.Status = domain.DeleteJobDone
+	if state.job.Error != "" {
+		state.job.Status = domain.DeleteJobFailed
+	}
+
+	return state.job.Status, true
+}
+
+// get return copy of user job
+func (j *deleteJobs) get(id string, userID int64) (*domain.DeleteJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	state, ok := j.jobs[id]
+	if !ok || state.job.UserID != userID {
+		return nil, false
+	}
+
+	return state.job.Clone(), true
+}
+
+// prune forget jobs finished before ttl
+func (j *deleteJobs) prune(now time.Time) {
+	for id, state := range j.jobs {
+		if state.job.Finished() && now.Sub(state.job.UpdatedAt) > j.ttl {
+			delete(j.jobs, id)
+		}
+	}
+}
@@ -2,20 +2,29 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
 
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
 	"github.com/korol8484/shortener/internal/app/user/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
 func TestDelete_BatchDelete(t *testing.T) {
@@ -37,7 +46,7 @@ func TestDelete_BatchDelete(t *testing.T) {
 		_ = store.Close()
 	}(store)
 
-	api, err := NewDelete(store, zap.L())
+	api, err := NewDelete(store, deleteStorage.NewMemoryStore(), zap.L())
 	require.NoError(t, err)
 	defer api.Close()
 
@@ -55,3 +64,244 @@ func TestDelete_BatchDelete(t *testing.T) {
 	defer res.Body.Close()
 	assert.Equal(t, res.StatusCode, http.StatusAccepted)
 }
+
+func TestDelete_Job(t *testing.T) {
+	store := memory.NewMemStore()
+	defer func(store Store) {
+		_ = store.Close()
+	}(store)
+
+	user := &domain.User{ID: 1}
+	require.NoError(t, store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, &domain.User{ID: 2}))
+
+	d, err := NewDelete(store, deleteStorage.NewMemoryStore(), zap.L())
+	require.NoError(t, err)
+	defer d.Close()
+
+	router := chi.NewRouter()
+	router.Delete("/api/user/urls", d.BatchDelete)
+	router.Get(deleteJobPath+"{id}", d.Job)
+
+	do := func(method, target, body string, userID int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), userID)))
+
+		return w
+	}
+
+	w := do(http.MethodDelete, "/api/user/urls", `["a", "b", "a", "c"]`, user.ID)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var job deleteJobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	require.NotEmpty(t, job.ID)
+	assert.Equal(t, deleteJobPath+job.ID, w.Header().Get("Location"))
+	assert.Len(t, job.Aliases, 3)
+
+	require.Eventually(t, func() bool {
+		w = do(http.MethodGet, w.Header().Get("Location"), "", user.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+
+		return job.Status == string(domain.DeleteJobDone)
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []aliasDeleteResponse{
+		{Alias: "a", Status: string(domain.AliasDeleteDone)},
+		{Alias: "b", Status: string(domain.AliasDeleteNotFound)},
+		{Alias: "c", Status: string(domain.AliasDeleteNotFound)},
+	}, job.Aliases)
+
+	w = do(http.MethodGet, deleteJobPath+job.ID, "", 2)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteJobs_Failed(t *testing.T) {
+	jobs := newDeleteJobs(time.Hour)
+
+	job := jobs.create([]string{"a", "b"}, 1, 2)
+	jobs.start(job.ID)
+	jobs.finish(job.ID, []string{"a"}, []string{"a"}, nil)
+
+	current, ok := jobs.get(job.ID, 1)
+	require.True(t, ok)
+	assert.Equal(t, domain.DeleteJobRunning, current.Status)
+
+	jobs.finish(job.ID, []string{"b"}, nil, errors.New("db down"))
+
+	current, ok = jobs.get(job.ID, 1)
+	require.True(t, ok)
+	assert.Equal(t, domain.DeleteJobFailed, current.Status)
+	assert.Equal(t, "db down", current.Error)
+	assert.Equal(t, domain.AliasDeleteDone, current.Aliases[0].Status)
+	assert.Equal(t, domain.AliasDeleteFailed, current.Aliases[1].Status)
+
+	jobs.prune(time.Now().Add(2 * time.Hour))
+	_, ok = jobs.get(job.ID, 1)
+	assert.False(t, ok)
+}
+
+// flakyStore fails BatchDelete given number of times
+type flakyStore struct {
+	Store
+	fails atomic.Int32
+}
+
+func (f *flakyStore) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	if f.fails.Add(-1) >= 0 {
+		return nil, errors.New("db down")
+	}
+
+	return f.Store.BatchDelete(ctx, aliases, userID)
+}
+
+func TestDelete_Durable(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	base := memory.NewMemStore()
+	require.NoError(t, base.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, base.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+
+	store := &flakyStore{Store: base}
+	store.fails.Store(2)
+
+	queue := deleteStorage.NewMemoryStore()
+	// job left by previous run
+	require.NoError(t, queue.Push(ctx, &domain.DeleteJob{
+		ID:      "replayed",
+		UserID:  user.ID,
+		Status:  domain.DeleteJobQueued,
+		Aliases: []*domain.AliasDelete{{Alias: "a", Status: domain.AliasDeletePending}},
+	}))
+
+	d := newDelete(store, queue, zap.L())
+	d.backoff = time.Millisecond
+	require.NoError(t, d.start())
+	defer d.Close()
+
+	job, err := d.Enqueue(ctx, []string{"b"}, user.ID)
+	require.NoError(t, err)
+
+	for _, id := range []string{"replayed", job.ID} {
+		require.Eventually(t, func() bool {
+			current, ok := d.ReadJob(id, user.ID)
+			require.True(t, ok)
+
+			return current.Status == domain.DeleteJobDone && current.Aliases[0].Status == domain.AliasDeleteDone
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		pending, pErr := queue.Pending(ctx)
+		require.NoError(t, pErr)
+
+		return len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, DeleteStats{Deleted: 2, Retried: 2}, d.Stats())
+}
+
+func TestDelete_StatsFailed(t *testing.T) {
+	store := &flakyStore{Store: memory.NewMemStore()}
+	store.fails.Store(100)
+
+	// batch waits in queue while workers are not started
+	idle := newDelete(store, deleteStorage.NewMemoryStore(), zap.L())
+	_, err := idle.Enqueue(context.Background(), []string{"a"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, DeleteStats{Queued: 1}, idle.Stats())
+
+	queue := deleteStorage.NewMemoryStore()
+
+	d := newDelete(store, queue, zap.L())
+	d.attempts = 1
+	require.NoError(t, d.start())
+	defer d.Close()
+
+	job, err := d.Enqueue(context.Background(), []string{"a"}, 1)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		current, ok := d.ReadJob(job.ID, 1)
+		require.True(t, ok)
+
+		return d.Stats() == DeleteStats{Failed: 1} && current.Status == domain.DeleteJobFailed
+	}, time.Second, 5*time.Millisecond)
+
+	// failed job is not acknowledged and is replayed on next start
+	pending, err := queue.Pending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, job.ID, pending[0].ID)
+}
+
+func TestDelete_CloseKeepsFailed(t *testing.T) {
+	ctx := context.Background()
+
+	store := &flakyStore{Store: memory.NewMemStore()}
+	store.fails.Store(100)
+
+	queue := deleteStorage.NewMemoryStore()
+
+	d := newDelete(store, queue, zap.L())
+	d.backoff = time.Hour
+	require.NoError(t, d.start())
+
+	job, err := d.Enqueue(ctx, []string{"a"}, 1)
+	require.NoError(t, err)
+
+	// close interrupts retry backoff, job stays in queue for next start
+	require.Eventually(t, func() bool {
+		return store.fails.Load() < 100
+	}, time.Second, 5*time.Millisecond)
+	d.Close()
+
+	pending, err := queue.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, job.ID, pending[0].ID)
+}
+
+func TestDelete_TraceLink(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+	})
+
+	d, err := NewDelete(memory.NewMemStore(), deleteStorage.NewMemoryStore(), zap.L())
+	require.NoError(t, err)
+	defer d.Close()
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	job, err := d.Enqueue(ctx, []string{"a"}, 1)
+	require.NoError(t, err)
+	request.End()
+
+	require.Eventually(t, func() bool {
+		current, ok := d.ReadJob(job.ID, 1)
+		return ok && current.Finished()
+	}, time.Second, 5*time.Millisecond)
+
+	var batch sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		for _, span := range sr.Ended() {
+			if span.Name() == "delete.batch" {
+				batch = span
+				return true
+			}
+		}
+
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	// batch is traced apart from request and linked to it
+	assert.False(t, batch.Parent().IsValid())
+	assert.NotEqual(t, request.SpanContext().TraceID(), batch.SpanContext().TraceID())
+	require.Len(t, batch.Links(), 1)
+	assert.Equal(t, request.SpanContext().SpanID(), batch.Links()[0].SpanContext.SpanID())
+}
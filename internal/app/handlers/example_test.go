@@ -10,7 +10,9 @@ import (
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/config"
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
 	"github.com/korol8484/shortener/internal/app/user/storage"
@@ -36,8 +38,8 @@ func Example() {
 	}(store)
 
 	// Create API Handlers
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
-	apiDelete, err := NewDelete(store, zap.L())
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
+	apiDelete, err := NewDelete(store, deleteStorage.NewMemoryStore(), zap.L())
 	if err != nil {
 		log.Fatal(err)
 	}
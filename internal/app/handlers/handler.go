@@ -4,14 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"hash/fnv"
 	"io"
-	"math/rand"
 	"net/http"
-	"net/url"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/storage"
 	"github.com/korol8484/shortener/internal/app/user/util"
@@ -22,9 +21,10 @@ const (
 	mimePlain = "text/plain"
 )
 
-// Config Return HTTP domain append to short URL
+// Config Return HTTP domain append to short URL and retention of deleted links
 type Config interface {
 	GetBaseShortURL() string
+	GetDeleteRetention() time.Duration
 }
 
 // Store Repository Interface
@@ -32,9 +32,15 @@ type Store interface {
 	Add(ctx context.Context, ent *domain.URL, user *domain.User) error
 	Read(ctx context.Context, alias string) (*domain.URL, error)
 	ReadByURL(ctx context.Context, URL string) (*domain.URL, error)
-	AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) error
+	AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error)
 	ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error)
-	BatchDelete(ctx context.Context, aliases []string, userID int64) error
+	ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error)
+	ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error)
+	BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error)
+	Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error)
+	Update(ctx context.Context, ent *domain.URL, user *domain.User) error
+	ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error)
+	CountURL(ctx context.Context) (int64, error)
 	Close() error
 }
 
@@ -42,11 +48,12 @@ type Store interface {
 type API struct {
 	store Store
 	cfg   Config
+	gen   alias.Generator
 }
 
 // NewAPI Factory
-func NewAPI(store Store, cfg Config) *API {
-	return &API{store: store, cfg: cfg}
+func NewAPI(store Store, cfg Config, gen alias.Generator) *API {
+	return &API{store: store, cfg: cfg, gen: gen}
 }
 
 // HandleShort Handler for one URL requested at plain text
@@ -63,19 +70,14 @@ func (a *API) HandleShort(w http.ResponseWriter, r *http.Request) {
 		_ = Body.Close()
 	}(r.Body)
 
-	ent, err := a.shortURL(string(body))
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
 	userID, ok := util.ReadUserIDFromCtx(r.Context())
 	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if err = a.store.Add(r.Context(), ent, &domain.User{ID: userID}); err != nil {
+	ent, err := ShortenURL(r.Context(), a.store, a.gen, &domain.URL{URL: string(body)}, &domain.User{ID: userID})
+	if err != nil {
 		if errors.Is(err, storage.ErrIssetURL) {
 			w.Header().Set("content-type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusConflict)
@@ -93,7 +95,7 @@ func (a *API) HandleShort(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleRedirect Handler plain text alias
-// Response HTTP redirect to short URL
+// Response HTTP redirect to short URL, 410 Gone for deleted or expired link
 func (a *API) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 	alias := chi.URLParam(r, "id")
 
@@ -108,41 +110,10 @@ func (a *API) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ent.Deleted {
+	if ent.Deleted || ent.Expired(time.Now()) {
 		w.WriteHeader(http.StatusGone)
 		return
 	}
 
 	http.Redirect(w, r, ent.URL, http.StatusTemporaryRedirect)
 }
-
-func (a *API) shortURL(shortURL string) (*domain.URL, error) {
-	parsedURL, err := url.Parse(shortURL)
-	if err != nil {
-		return nil, err
-	}
-
-	ent := &domain.URL{
-		URL:   parsedURL.String(),
-		Alias: GenAlias(6, shortURL),
-	}
-
-	return ent, nil
-}
-
-// GenAlias - Create alias length n as a hash of the string
-func GenAlias(keyLen int, shortURL string) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-
-	h := fnv.New64()
-	h.Write([]byte(shortURL))
-
-	r := rand.New(rand.NewSource(int64(h.Sum64())))
-
-	keyMap := make([]byte, keyLen)
-	for i := range keyMap {
-		keyMap[i] = charset[r.Intn(len(charset))]
-	}
-
-	return string(keyMap)
-}
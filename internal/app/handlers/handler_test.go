@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/config"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
@@ -63,7 +65,7 @@ func TestAPI_HandleShort(t *testing.T) {
 		_ = store.Close()
 	}(store)
 
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
 	router.Post("/", api.HandleShort)
 
 	client := &http.Client{}
@@ -98,7 +100,7 @@ func TestAPI_HandleRedirect(t *testing.T) {
 		_ = store.Close()
 	}(store)
 
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
 	router.Get("/{id}", api.HandleRedirect)
 
 	err := api.store.Add(context.Background(), &domain.URL{
@@ -107,6 +109,13 @@ func TestAPI_HandleRedirect(t *testing.T) {
 	}, &domain.User{ID: 1})
 	require.NoError(t, err)
 
+	err = api.store.Add(context.Background(), &domain.URL{
+		URL:       "http://www.ya1.ru",
+		Alias:     "7A1S4z",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
 	client := &http.Client{
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -151,6 +160,15 @@ func TestAPI_HandleRedirect(t *testing.T) {
 				alias:       "111111",
 			},
 		},
+		{
+			name: "expired",
+			want: want{
+				code:        http.StatusGone,
+				method:      http.MethodGet,
+				expectedURL: "",
+				alias:       "7A1S4z",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -186,7 +204,7 @@ func TestAPI_ShortenJson(t *testing.T) {
 		_ = store.Close()
 	}(store)
 
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
 	router.Post("/json", api.ShortenJSON)
 
 	client := &http.Client{}
@@ -222,6 +240,36 @@ func TestAPI_ShortenJson(t *testing.T) {
 			contentType: "application/json",
 			body:        "{\"url\": \"https://practicum.yandex.ru\"}",
 		}},
+		{name: "success_custom_alias", want: want{
+			method:      http.MethodPost,
+			code:        201,
+			contentType: "application/json",
+			body:        "{\"url\": \"https://ya.ru\", \"alias\": \"spring-sale\"}",
+		}},
+		{name: "custom_alias_taken", want: want{
+			method:      http.MethodPost,
+			code:        409,
+			contentType: "application/json",
+			body:        "{\"url\": \"https://ya1.ru\", \"alias\": \"spring-sale\"}",
+		}},
+		{name: "invalid_ttl", want: want{
+			method:      http.MethodPost,
+			code:        400,
+			contentType: "application/json",
+			body:        "{\"url\": \"https://ya2.ru\", \"ttl\": \"-1h\"}",
+		}},
+		{name: "success_ttl", want: want{
+			method:      http.MethodPost,
+			code:        201,
+			contentType: "application/json",
+			body:        "{\"url\": \"https://ya2.ru\", \"ttl\": \"1h\"}",
+		}},
+		{name: "custom_alias_reserved", want: want{
+			method:      http.MethodPost,
+			code:        400,
+			contentType: "application/json",
+			body:        "{\"url\": \"https://ya1.ru\", \"alias\": \"ping\"}",
+		}},
 	}
 
 	for _, test := range tests {
@@ -242,16 +290,3 @@ func TestAPI_ShortenJson(t *testing.T) {
 		})
 	}
 }
-
-func TestGenAlias(t *testing.T) {
-	alias := GenAlias(6, "testString")
-	if alias != "Jlf8iW" {
-		t.Fatal("invalid alias generated")
-	}
-}
-
-func BenchmarkGenAlias(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		GenAlias(5, "https://ya.ru")
-	}
-}
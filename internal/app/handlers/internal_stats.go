@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// UserCounter user repository which can count users
+type UserCounter interface {
+	CountUsers(ctx context.Context) (int64, error)
+}
+
+type internalStatsResponse struct {
+	URLs  int64 `json:"urls"`
+	Users int64 `json:"users"`
+}
+
+// InternalStats Handler for service statistic, must be served from trusted subnet
+// Returns:
+//
+//	{
+//	    "urls": 10,
+//	    "users": 2
+//	}
+func InternalStats(store Store, users UserCounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		urls, err := store.CountURL(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		usersCount, err := users.CountUsers(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		b, err := json.Marshal(&internalStatsResponse{URLs: urls, Users: usersCount})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("content-type", mimeJSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(b)
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func TestInternalStats(t *testing.T) {
+	store := memory.NewMemStore()
+	users := storage.NewMemoryStore()
+
+	user, err := users.NewUser(context.Background())
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	InternalStats(store, users)(w, httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"urls": 1, "users": 1}`, w.Body.String())
+}
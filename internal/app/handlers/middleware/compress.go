@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/korol8484/shortener/internal/app/util"
+)
+
+var compressibleContentTypes = []string{
+	"text/html",
+	"application/json",
+}
+
+// errUnsupportedEncoding - Ошибка что запрос сжат неизвестным способом
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// CompressorConfig - compression config interface
+type CompressorConfig interface {
+	GetCompressMinSize() int
+	GetCompressTypes() string
+}
+
+// Compressor is a middleware that compresses response
+// body of a given content types to a data format negotiated
+// by Accept-Encoding request header and decodes request body by Content-Encoding.
+type Compressor struct {
+	encoders     []Encoder
+	byName       map[string]Encoder
+	allowedTypes map[string]struct{}
+	minSize      int
+}
+
+// NewCompressor - Factory for compression middleware, encoders are preferred in given order
+// when client weights them equally, DefaultEncoders are used when encoders are not given
+func NewCompressor(cfg CompressorConfig, encoders ...Encoder) *Compressor {
+	if len(encoders) == 0 {
+		encoders = DefaultEncoders()
+	}
+
+	types := compressibleContentTypes
+	if cfg.GetCompressTypes() != "" {
+		types = strings.Split(cfg.GetCompressTypes(), ",")
+	}
+
+	c := &Compressor{
+		encoders:     encoders,
+		byName:       make(map[string]Encoder, len(encoders)),
+		allowedTypes: make(map[string]struct{}, len(types)),
+		minSize:      cfg.GetCompressMinSize(),
+	}
+
+	for _, e := range encoders {
+		c.byName[e.Name()] = e
+	}
+
+	for _, t := range types {
+		c.allowedTypes[strings.ToLower(strings.TrimSpace(t))] = struct{}{}
+	}
+
+	return c
+}
+
+// writer states
+const (
+	stateHeader = iota
+	stateBuffer
+	stateCompress
+	statePlain
+)
+
+type compressWriter struct {
+	http.ResponseWriter
+	c       *Compressor
+	encoder Encoder
+	zw      io.WriteCloser
+	// buf body kept until it reaches min size
+	buf   []byte
+	code  int
+	state int
+}
+
+// compressible check response of status code can be compressed, Vary is added for content types
+// which are compressed for clients accepting compression
+func (c *compressWriter) compressible(code int) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+
+	if c.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+
+	cT := strings.ToLower(util.FilterContentType(c.Header().Get("Content-Type")))
+	if _, ok := c.c.allowedTypes[cT]; !ok {
+		return false
+	}
+
+	c.Header().Add("Vary", "Accept-Encoding")
+
+	return c.encoder != nil
+}
+
+// WriteHeader compression header interceptor, header is delayed while body is shorter than min size
+func (c *compressWriter) WriteHeader(code int) {
+	if c.state != stateHeader {
+		return
+	}
+
+	c.code = code
+
+	if !c.compressible(code) {
+		c.state = statePlain
+		c.ResponseWriter.WriteHeader(code)
+
+		return
+	}
+
+	c.state = stateBuffer
+
+	size, err := strconv.Atoi(c.Header().Get("Content-Length"))
+	if c.c.minSize == 0 || (err == nil && size >= c.c.minSize) {
+		_ = c.startCompress()
+	}
+}
+
+// Write writes the data to the connection as part of an HTTP reply.
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.state == stateHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	switch c.state {
+	case stateCompress:
+		return c.zw.Write(p)
+	case stateBuffer:
+		c.buf = append(c.buf, p...)
+		if len(c.buf) < c.c.minSize {
+			return len(p), nil
+		}
+
+		if err := c.startCompress(); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	default:
+		return c.ResponseWriter.Write(p)
+	}
+}
+
+// startCompress write compressed response header and buffered body
+func (c *compressWriter) startCompress() error {
+	c.Header().Set("Content-Encoding", c.encoder.Name())
+	c.Header().Del("Content-Length")
+	c.ResponseWriter.WriteHeader(c.code)
+
+	c.state = stateCompress
+	c.zw = c.encoder.Writer(c.ResponseWriter)
+
+	buf := c.buf
+	c.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := c.zw.Write(buf)
+
+	return err
+}
+
+// Flush sends buffered data to client, body shorter than min size is compressed too
+func (c *compressWriter) Flush() {
+	if c.state == stateBuffer {
+		_ = c.startCompress()
+	}
+
+	if f, ok := c.zw.(interface{ Flush() error }); ok && c.state == stateCompress {
+		_ = f.Flush()
+	}
+
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap underlying writer for http.ResponseController
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Close finish compressed stream, body shorter than min size is sent as is
+func (c *compressWriter) Close() error {
+	switch c.state {
+	case stateBuffer:
+		c.state = statePlain
+		c.Header().Set("Content-Length", strconv.Itoa(len(c.buf)))
+		c.ResponseWriter.WriteHeader(c.code)
+
+		_, err := c.ResponseWriter.Write(c.buf)
+
+		return err
+	case stateCompress:
+		c.state = statePlain
+		err := c.zw.Close()
+		c.encoder.Release(c.zw)
+
+		return err
+	default:
+		return nil
+	}
+}
+
+// negotiate select encoder with max weight in Accept-Encoding, nil - response is not compressed
+func (c *Compressor) negotiate(accept []string) Encoder {
+	weights := parseAcceptEncoding(accept)
+
+	var (
+		best  Encoder
+		bestQ float64
+		// wild weight of codings not listed in header
+		wild = -1.0
+	)
+
+	if q, ok := weights["*"]; ok {
+		wild = q
+	}
+
+	for _, e := range c.encoders {
+		q, ok := weights[e.Name()]
+		if !ok {
+			q = wild
+		}
+
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+
+	return best
+}
+
+// parseAcceptEncoding weights of codings in Accept-Encoding header, coding without q has weight 1.
+// Codings with invalid weight are skipped
+func parseAcceptEncoding(values []string) map[string]float64 {
+	weights := make(map[string]float64)
+
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(part, ";")
+
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			q, ok := parseQ(params)
+			if !ok {
+				continue
+			}
+
+			weights[name] = q
+		}
+	}
+
+	return weights
+}
+
+// parseQ weight from coding parameters like "q=0.5"
+func parseQ(params string) (float64, bool) {
+	for _, p := range strings.Split(params, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if !strings.EqualFold(k, "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+
+		return q, true
+	}
+
+	return 1, true
+}
+
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close is the interface that wraps the basic Close method.
+func (d *decodedBody) Close() error {
+	var errs []error
+	for _, c := range d.closers {
+		errs = append(errs, c.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// decodeBody replace request body with decoded one, codings of Content-Encoding are removed in reverse order
+func (c *Compressor) decodeBody(r *http.Request) error {
+	header := r.Header.Values("Content-Encoding")
+	if len(header) == 0 {
+		return nil
+	}
+
+	codings := strings.Split(strings.Join(header, ","), ",")
+	body := &decodedBody{Reader: r.Body, closers: []io.Closer{r.Body}}
+
+	for i := len(codings) - 1; i >= 0; i-- {
+		name := strings.ToLower(strings.TrimSpace(codings[i]))
+		if name == "" || name == "identity" {
+			continue
+		}
+
+		e, ok := c.byName[name]
+		if !ok {
+			_ = body.Close()
+			return errUnsupportedEncoding
+		}
+
+		zr, err := e.Reader(body.Reader)
+		if err != nil {
+			_ = body.Close()
+			return err
+		}
+
+		body.Reader = zr
+		// decoders are closed before the request body
+		body.closers = append([]io.Closer{zr}, body.closers...)
+	}
+
+	r.Body = body
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+
+	return nil
+}
+
+// Handler returns a new middleware that will compress the response based on the
+// current Compressor.
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := c.decodeBody(r); err != nil {
+			if errors.Is(err, errUnsupportedEncoding) {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		wr := &compressWriter{ResponseWriter: w, c: c}
+
+		// response of HEAD has no body to compress
+		if r.Method != http.MethodHead {
+			wr.encoder = c.negotiate(r.Header.Values("Accept-Encoding"))
+		}
+
+		defer func(wr *compressWriter) {
+			_ = wr.Close()
+		}(wr)
+
+		next.ServeHTTP(wr, r)
+	})
+}
@@ -0,0 +1,365 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-chi/chi/v5"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/config"
+)
+
+func TestCompressor(t *testing.T) {
+	r := chi.NewRouter()
+
+	compressor := NewCompressor(&config.App{})
+	r.Use(compressor.Handler)
+
+	r.Get("/get-json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("htmlstring"))
+	})
+
+	r.Get("/get-html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("htmlstring"))
+	})
+
+	r.Get("/get-text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("htmlstring"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	type want struct {
+		path              string
+		expectedEncoding  string
+		acceptedEncodings []string
+		responseBody      string
+	}
+
+	tests := []struct {
+		name string
+		want
+	}{
+		{
+			name: "no_expected_encodings_html",
+			want: want{
+				path:              "/get-html",
+				acceptedEncodings: nil,
+				expectedEncoding:  "",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "gzip_only_encoding_html",
+			want: want{
+				path:              "/get-html",
+				acceptedEncodings: []string{"gzip"},
+				expectedEncoding:  "gzip",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "no_expected_encodings_json",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: nil,
+				expectedEncoding:  "",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "gzip_preferred_to_deflate_json",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"gzip", "deflate"},
+				expectedEncoding:  "gzip",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "deflate_only_encoding",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"deflate"},
+				expectedEncoding:  "deflate",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "max_weight_encoding",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"gzip;q=0.5", "zstd;q=0.8", "br;q=0.1"},
+				expectedEncoding:  "zstd",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "server_preference_on_equal_weight",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"gzip", "deflate", "br", "zstd"},
+				expectedEncoding:  "br",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "wildcard_except_refused",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"br;q=0", "*"},
+				expectedEncoding:  "zstd",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "refused_encoding",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"gzip;q=0", "identity"},
+				expectedEncoding:  "",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "invalid_weight",
+			want: want{
+				path:              "/get-json",
+				acceptedEncodings: []string{"gzip;q=abc"},
+				expectedEncoding:  "",
+				responseBody:      "htmlstring",
+			},
+		},
+		{
+			name: "not_compressible_type",
+			want: want{
+				path:              "/get-text",
+				acceptedEncodings: []string{"gzip"},
+				expectedEncoding:  "",
+				responseBody:      "htmlstring",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			resp, respString := testRequestWithAcceptedEncodings(t, ts, "GET", tc.path, tc.acceptedEncodings...)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.responseBody, respString)
+			assert.Equal(t, tc.expectedEncoding, resp.Header.Get("Content-Encoding"))
+		})
+	}
+}
+
+func testRequestWithAcceptedEncodings(t *testing.T, ts *httptest.Server, method, path string, encodings ...string) (*http.Response, string) {
+	req, err := http.NewRequest(method, ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+		return nil, ""
+	}
+	if len(encodings) > 0 {
+		encodingsString := strings.Join(encodings, ",")
+		req.Header.Set("Accept-Encoding", encodingsString)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+		return nil, ""
+	}
+
+	respBody := decodeResponseBody(t, resp)
+
+	return resp, respBody
+}
+
+func decodeResponseBody(t *testing.T, resp *http.Response) string {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		reader, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		reader, err = zlib.NewReader(resp.Body)
+	case "br":
+		reader = io.NopCloser(brotli.NewReader(resp.Body))
+	case "zstd":
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(resp.Body)
+		if err == nil {
+			reader = zr.IOReadCloser()
+		}
+	default:
+		reader = resp.Body
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respBody, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+		return ""
+	}
+	reader.Close()
+
+	return string(respBody)
+}
+
+func TestCompressor_MinSize(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(NewCompressor(&config.App{CompressMinSize: 20, CompressTypes: "text/plain"}).Handler)
+
+	r.Get("/small", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("small"))
+	})
+
+	r.Get("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		for i := 0; i < 5; i++ {
+			_, _ = w.Write([]byte("chunk"))
+		}
+	})
+
+	r.Get("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(strings.Repeat("a", 30)))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequestWithAcceptedEncodings(t, ts, "GET", "/small", "gzip")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(t, "small", body)
+
+	resp, body = testRequestWithAcceptedEncodings(t, ts, "GET", "/big", "gzip")
+	defer resp.Body.Close()
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, strings.Repeat("chunk", 5), body)
+
+	// only configured types are compressed
+	resp, _ = testRequestWithAcceptedEncodings(t, ts, "GET", "/json", "gzip")
+	defer resp.Body.Close()
+
+	assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+}
+
+func TestCompressor_DecodeBody(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(NewCompressor(&config.App{}).Handler)
+
+	r.Post("/echo", func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write(b)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	post := func(encoding string, body []byte) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/echo", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Encoding", encoding)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp, string(b)
+	}
+
+	for _, e := range DefaultEncoders() {
+		t.Run(e.Name(), func(t *testing.T) {
+			resp, body := post(e.Name(), encode(t, e, []byte("body")))
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "body", body)
+		})
+	}
+
+	// codings are removed in reverse order
+	resp, body := post("deflate, gzip", encode(t, GzipEncoder(), encode(t, DeflateEncoder(), []byte("body"))))
+	defer resp.Body.Close()
+
+	assert.Equal(t, "body", body)
+
+	resp, _ = post("compress", []byte("body"))
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, _ = post("gzip", []byte("body"))
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEncoder_Pool(t *testing.T) {
+	e := GzipEncoder()
+
+	// released writer is reset to new destination
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+
+		zw := e.Writer(&buf)
+		_, err := zw.Write([]byte("body"))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		e.Release(zw)
+
+		zr, err := e.Reader(&buf)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, "body", string(b))
+	}
+}
+
+func encode(t *testing.T, e Encoder, body []byte) []byte {
+	var buf bytes.Buffer
+
+	zw := e.Writer(&buf)
+	_, err := zw.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	e.Release(zw)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoder content coding of Accept-Encoding and Content-Encoding headers
+type Encoder interface {
+	// Name token of coding, like gzip
+	Name() string
+	// Writer compressing writer to w, it must be passed to Release after Close
+	Writer(w io.Writer) io.WriteCloser
+	// Release put closed writer back to pool
+	Release(zw io.WriteCloser)
+	// Reader decompressing reader of request body
+	Reader(r io.Reader) (io.ReadCloser, error)
+}
+
+// ResetWriter compressing writer reused for another destination after Reset
+type ResetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+type pooledEncoder struct {
+	name      string
+	pool      sync.Pool
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+// NewEncoder Factory, writers created by newWriter are kept in pool and reset to destination before use
+func NewEncoder(name string, newWriter func() ResetWriter, newReader func(r io.Reader) (io.ReadCloser, error)) Encoder {
+	return &pooledEncoder{
+		name: name,
+		pool: sync.Pool{New: func() any {
+			return newWriter()
+		}},
+		newReader: newReader,
+	}
+}
+
+// Name token of coding
+func (e *pooledEncoder) Name() string {
+	return e.name
+}
+
+// Writer compressing writer from pool
+func (e *pooledEncoder) Writer(w io.Writer) io.WriteCloser {
+	zw := e.pool.Get().(ResetWriter)
+	zw.Reset(w)
+
+	return zw
+}
+
+// Release put writer back to pool, reference to last destination is dropped
+func (e *pooledEncoder) Release(zw io.WriteCloser) {
+	if rw, ok := zw.(ResetWriter); ok {
+		rw.Reset(io.Discard)
+		e.pool.Put(rw)
+	}
+}
+
+// Reader decompressing reader
+func (e *pooledEncoder) Reader(r io.Reader) (io.ReadCloser, error) {
+	return e.newReader(r)
+}
+
+// DefaultEncoders supported codings in order of preference
+func DefaultEncoders() []Encoder {
+	return []Encoder{BrotliEncoder(), ZstdEncoder(), GzipEncoder(), DeflateEncoder()}
+}
+
+// GzipEncoder gzip coding
+func GzipEncoder() Encoder {
+	return NewEncoder("gzip", func() ResetWriter {
+		return gzip.NewWriter(nil)
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+// DeflateEncoder deflate coding, HTTP deflate is zlib stream
+func DeflateEncoder() Encoder {
+	return NewEncoder("deflate", func() ResetWriter {
+		return zlib.NewWriter(nil)
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	})
+}
+
+// BrotliEncoder br coding
+func BrotliEncoder() Encoder {
+	return NewEncoder("br", func() ResetWriter {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	})
+}
+
+// ZstdEncoder zstd coding
+func ZstdEncoder() Encoder {
+	return NewEncoder("zstd", func() ResetWriter {
+		// options are valid, so error is not possible
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+
+		return zw
+	}, func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return zr.IOReadCloser(), nil
+	})
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
@@ -13,6 +15,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/logger"
 	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
@@ -21,14 +24,22 @@ type UserAddRepository interface {
 	NewUser(ctx context.Context) (*domain.User, error)
 }
 
+// Config - jwt config interface
+type Config interface {
+	GetJWTKeys() string
+	GetJWTExpire() time.Duration
+}
+
 // Jwt middleware
 type Jwt struct {
-	secret     string
-	expire     time.Duration
-	userRep    UserAddRepository
-	signMethod jwt.SigningMethod
-	tokenName  string
-	logger     *zap.Logger
+	// signKey signs new tokens
+	signKey *Key
+	// keys verify tokens by kid header
+	keys      map[string]*Key
+	expire    time.Duration
+	userRep   UserAddRepository
+	tokenName string
+	logger    *zap.Logger
 }
 
 type claims struct {
@@ -41,16 +52,73 @@ var (
 	errInvalidToken = errors.New("token not valid")
 )
 
-// NewJwt implements a simple middleware handler for adding JWT auth.
+// defaultExpire token lifetime when it is not configured
+const defaultExpire = 100 * time.Hour
+
+// NewJwt implements a simple middleware handler for adding JWT auth with HS256 secret.
 func NewJwt(userRep UserAddRepository, logger *zap.Logger, secret string) *Jwt {
-	return &Jwt{
-		secret:     secret,
-		expire:     100 * time.Hour,
-		userRep:    userRep,
-		signMethod: jwt.SigningMethodHS256,
-		tokenName:  "Authorization",
-		logger:     logger,
+	j, _ := NewJwtWithKeys(userRep, logger, defaultExpire, NewHMACKey("", secret))
+	return j
+}
+
+// NewJwtFromConfig Factory, keys and token lifetime are read from config.
+// Without configured keys random HS256 key is generated, so tokens don't survive restart
+func NewJwtFromConfig(userRep UserAddRepository, logger *zap.Logger, cfg Config) (*Jwt, error) {
+	if strings.TrimSpace(cfg.GetJWTKeys()) == "" {
+		key, err := randomKey()
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Warn(
+			"JWT KEYS ARE NOT CONFIGURED, RANDOM KEY IS USED: tokens are invalidated on restart " +
+				"and not accepted by other instances, set -jwt-keys or JWT_KEYS",
+		)
+
+		return NewJwtWithKeys(userRep, logger, cfg.GetJWTExpire(), key)
+	}
+
+	keys, err := ParseKeys(cfg.GetJWTKeys())
+	if err != nil {
+		return nil, err
 	}
+
+	return NewJwtWithKeys(userRep, logger, cfg.GetJWTExpire(), keys...)
+}
+
+// NewJwtWithKeys Factory, first key signs new tokens, all keys verify tokens selected by kid header.
+// Keeping previous keys after the first one allows to rotate keys without logging out users
+func NewJwtWithKeys(userRep UserAddRepository, logger *zap.Logger, expire time.Duration, keys ...*Key) (*Jwt, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	if keys[0].sign == nil {
+		return nil, fmt.Errorf("jwt key %s can't sign tokens", keys[0].ID)
+	}
+
+	if expire <= 0 {
+		expire = defaultExpire
+	}
+
+	j := &Jwt{
+		signKey:   keys[0],
+		keys:      make(map[string]*Key, len(keys)),
+		expire:    expire,
+		userRep:   userRep,
+		tokenName: "Authorization",
+		logger:    logger,
+	}
+
+	for _, k := range keys {
+		if _, ok := j.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id: %s", k.ID)
+		}
+
+		j.keys[k.ID] = k
+	}
+
+	return j, nil
 }
 
 // HandlerRead returns a middleware that will read and validate JWT token from header or cookie
@@ -61,7 +129,7 @@ func (j *Jwt) HandlerRead() func(next http.Handler) http.Handler {
 			if token == "" {
 				cToken, err := r.Cookie(j.tokenName)
 				if err != nil {
-					j.logger.Error("cookie not found")
+					logger.FromContext(r.Context(), j.logger).Error("cookie not found")
 					w.WriteHeader(http.StatusUnauthorized)
 					return
 				}
@@ -71,12 +139,12 @@ func (j *Jwt) HandlerRead() func(next http.Handler) http.Handler {
 
 			claim, err := j.loadClaims(token)
 			if err != nil {
-				j.logger.Error("token not valid")
+				logger.FromContext(r.Context(), j.logger).Error("token not valid")
 				w.WriteHeader(http.StatusUnauthorized)
 				return
 			}
 
-			next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), claim.UserID)))
+			next.ServeHTTP(w, withUser(r, claim.UserID))
 		})
 	}
 }
@@ -105,39 +173,63 @@ func (j *Jwt) HandlerSet() func(next http.Handler) http.Handler {
 					return
 				}
 
-				next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+				next.ServeHTTP(w, withUser(r, user.ID))
 				return
 			}
 
 			claim, err := j.loadClaims(token)
 			if err != nil {
-				if !errors.Is(err, errInvalidToken) {
-					w.WriteHeader(http.StatusBadRequest)
-					return
-				}
-
+				// token of other instance or rotated out key, user gets new one instead of error
 				user, err := j.setNewToken(r.Context(), w)
 				if err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
 					return
 				}
 
-				next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+				next.ServeHTTP(w, withUser(r, user.ID))
 				return
 			}
 
-			next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), claim.UserID)))
+			next.ServeHTTP(w, withUser(r, claim.UserID))
 		})
 	}
 }
 
-func (j *Jwt) setNewToken(ctx context.Context, w http.ResponseWriter) (*domain.User, error) {
+// NewUserToken create new user and sign token for him
+func (j *Jwt) NewUserToken(ctx context.Context) (*domain.User, string, error) {
 	user, err := j.userRep.NewUser(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	token, err := j.buildJWTString(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, token, nil
+}
+
+// UserIDFromToken validate token and return user ID from claims
+func (j *Jwt) UserIDFromToken(token string) (int64, error) {
+	claim, err := j.loadClaims(token)
+	if err != nil {
+		return 0, err
+	}
+
+	return claim.UserID, nil
+}
+
+// TokenFromBearer extract token from "Bearer <token>" string
+func TokenFromBearer(bearer string) string {
+	if len(bearer) > 7 && strings.ToUpper(bearer[0:6]) == "BEARER" {
+		return bearer[7:]
+	}
+	return ""
+}
+
+func (j *Jwt) setNewToken(ctx context.Context, w http.ResponseWriter) (*domain.User, error) {
+	user, token, err := j.NewUserToken(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -157,14 +249,18 @@ func (j *Jwt) setNewToken(ctx context.Context, w http.ResponseWriter) (*domain.U
 }
 
 func (j *Jwt) buildJWTString(user *domain.User) (string, error) {
-	token := jwt.NewWithClaims(j.signMethod, claims{
+	token := jwt.NewWithClaims(j.signKey.Method, claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expire)),
 		},
 		UserID: user.ID,
 	})
 
-	tokenString, err := token.SignedString([]byte(j.secret))
+	if j.signKey.ID != "" {
+		token.Header["kid"] = j.signKey.ID
+	}
+
+	tokenString, err := token.SignedString(j.signKey.sign)
 	if err != nil {
 		return "", err
 	}
@@ -176,14 +272,24 @@ func (j *Jwt) loadClaims(tokenStr string) (*claims, error) {
 	claims := &claims{}
 	token, err := jwt.ParseWithClaims(tokenStr, claims,
 		func(t *jwt.Token) (interface{}, error) {
-			if t.Method.Alg() != j.signMethod.Alg() {
+			// tokens without kid are signed by key with empty id
+			kid, _ := t.Header["kid"].(string)
+
+			key, ok := j.keys[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown key id: %s", kid)
+			}
+
+			if t.Method.Alg() != key.Method.Alg() {
 				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 			}
-			return []byte(j.secret), nil
+
+			return key.verify, nil
 		})
 
+	// malformed, expired, foreign or signed by unknown key token is not valid, new one is issued
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", errInvalidToken, err)
 	}
 
 	if !token.Valid {
@@ -195,9 +301,22 @@ func (j *Jwt) loadClaims(tokenStr string) (*claims, error) {
 
 func (j *Jwt) tokenFromHeader(r *http.Request) string {
 	// Get token from authorization header.
-	bearer := r.Header.Get(j.tokenName)
-	if len(bearer) > 7 && strings.ToUpper(bearer[0:6]) == "BEARER" {
-		return bearer[7:]
+	return TokenFromBearer(r.Header.Get(j.tokenName))
+}
+
+// randomKey HS256 key with random secret
+func randomKey() (*Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("can't generate jwt key: %w", err)
 	}
-	return ""
+
+	return NewHMACKey("", hex.EncodeToString(secret)), nil
+}
+
+// withUser put user ID in request context and in fields of request scoped logger
+func withUser(r *http.Request, userID int64) *http.Request {
+	logger.AddFields(r.Context(), zap.Int64("user_id", userID))
+
+	return r.WithContext(util.SetUserIDToCtx(r.Context(), userID))
 }
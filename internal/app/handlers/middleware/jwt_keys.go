@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key JWT signing key, selected by kid header of token
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// sign secret or private key, nil for verify only key
+	sign interface{}
+	// verify secret or public key
+	verify interface{}
+}
+
+var (
+	// ErrNoKeys - Ошибка что не задан ни один ключ подписи
+	ErrNoKeys = errors.New("no jwt keys")
+	// ErrKeySpec - Ошибка что ключ задан в неверном формате
+	ErrKeySpec = errors.New("jwt key must be kid:alg:value")
+)
+
+// NewHMACKey HS256 key with shared secret
+func NewHMACKey(kid, secret string) *Key {
+	return &Key{ID: kid, Method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+}
+
+// NewPEMKey RS256 or EdDSA key from PEM file. File with public key only
+// makes verify only key, used to accept tokens of rotated out key
+func NewPEMKey(kid, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read jwt key %s: %w", kid, err)
+	}
+
+	key := &Key{ID: kid}
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+
+		if private, pErr := jwt.ParseRSAPrivateKeyFromPEM(data); pErr == nil {
+			key.sign, key.verify = private, &private.PublicKey
+			return key, nil
+		}
+
+		key.verify, err = jwt.ParseRSAPublicKeyFromPEM(data)
+	case jwt.SigningMethodEdDSA.Alg():
+		key.Method = jwt.SigningMethodEdDSA
+
+		if private, pErr := jwt.ParseEdPrivateKeyFromPEM(data); pErr == nil {
+			key.sign = private
+			key.verify, err = publicOf(private)
+
+			return key, err
+		}
+
+		key.verify, err = jwt.ParseEdPublicKeyFromPEM(data)
+	default:
+		return nil, fmt.Errorf("unsupported jwt key algorithm: %s", alg)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't parse jwt key %s: %w", kid, err)
+	}
+
+	return key, nil
+}
+
+// ParseKeys parse comma separated keys in format kid:alg:value,
+// value is secret for HS256 and path to PEM file for RS256 and EdDSA
+// Example: 2024-05:RS256:/etc/shortener/jwt.pem,2024-01:HS256:secret
+func ParseKeys(spec string) ([]*Key, error) {
+	var keys []*Key
+
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 3)
+		if len(fields) != 3 || fields[2] == "" {
+			return nil, fmt.Errorf("%w: %q", ErrKeySpec, part)
+		}
+
+		if fields[1] == jwt.SigningMethodHS256.Alg() {
+			keys = append(keys, NewHMACKey(fields[0], fields[2]))
+			continue
+		}
+
+		key, err := NewPEMKey(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	return keys, nil
+}
+
+func publicOf(private interface{}) (interface{}, error) {
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("can't get public key")
+	}
+
+	return signer.Public(), nil
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func writePEM(t *testing.T, name, typ string, der []byte) string {
+	p := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600))
+
+	return p
+}
+
+func TestJwt_Rotation(t *testing.T) {
+	old, err := NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, NewHMACKey("old", "secret-1"))
+	require.NoError(t, err)
+
+	oldToken, err := old.buildJWTString(&domain.User{ID: 7})
+	require.NoError(t, err)
+
+	legacyToken, err := NewJwt(storage.NewMemoryStore(), zap.L(), "legacy").buildJWTString(&domain.User{ID: 8})
+	require.NoError(t, err)
+
+	rotated, err := NewJwtWithKeys(
+		storage.NewMemoryStore(), zap.L(), time.Hour,
+		NewHMACKey("new", "secret-2"), NewHMACKey("old", "secret-1"), NewHMACKey("", "legacy"),
+	)
+	require.NoError(t, err)
+
+	id, err := rotated.UserIDFromToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+
+	id, err = rotated.UserIDFromToken(legacyToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), id)
+
+	newToken, err := rotated.buildJWTString(&domain.User{ID: 9})
+	require.NoError(t, err)
+
+	_, err = old.UserIDFromToken(newToken)
+	assert.Error(t, err)
+}
+
+func TestJwt_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	edDer, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	edPubDer, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	require.NoError(t, err)
+
+	rsaPath := writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	edPath := writePEM(t, "ed.pem", "PRIVATE KEY", edDer)
+	edPubPath := writePEM(t, "ed.pub", "PUBLIC KEY", edPubDer)
+
+	for _, alg := range []string{"RS256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			p := rsaPath
+			if alg == "EdDSA" {
+				p = edPath
+			}
+
+			key, err := NewPEMKey("k1", alg, p)
+			require.NoError(t, err)
+
+			j, err := NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, key)
+			require.NoError(t, err)
+
+			token, err := j.buildJWTString(&domain.User{ID: 3})
+			require.NoError(t, err)
+
+			id, err := j.UserIDFromToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), id)
+		})
+	}
+
+	pub, err := NewPEMKey("k1", "EdDSA", edPubPath)
+	require.NoError(t, err)
+
+	_, err = NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, pub)
+	assert.Error(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("a:HS256:s:e:c, b:HS256:x")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "a", keys[0].ID)
+	assert.Equal(t, []byte("s:e:c"), keys[0].sign)
+
+	_, err = ParseKeys("")
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	_, err = ParseKeys("a:HS256")
+	assert.ErrorIs(t, err, ErrKeySpec)
+
+	_, err = ParseKeys("a:ES256:/tmp/none")
+	assert.Error(t, err)
+
+	_, err = NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), 0, NewHMACKey("a", "1"), NewHMACKey("a", "2"))
+	assert.Error(t, err)
+}
+
+func TestNewJwtFromConfig_RandomKey(t *testing.T) {
+	withLogger(t, zapcore.WarnLevel, nil, func(l *zap.Logger, logs *observer.ObservedLogs, t *testing.T) {
+		first, err := NewJwtFromConfig(storage.NewMemoryStore(), l, &config.App{JWTExpire: time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, 1, logs.Len())
+
+		second, err := NewJwtFromConfig(storage.NewMemoryStore(), l, &config.App{JWTExpire: time.Hour})
+		require.NoError(t, err)
+
+		token, err := first.buildJWTString(&domain.User{ID: 1})
+		require.NoError(t, err)
+
+		id, err := first.UserIDFromToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), id)
+
+		// keys are random per instance, no shared default secret
+		_, err = second.UserIDFromToken(token)
+		assert.Error(t, err)
+	})
+}
@@ -1,16 +1,21 @@
 package middleware
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/user/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
 func TestSample(t *testing.T) {
@@ -32,7 +37,8 @@ func TestSample(t *testing.T) {
 
 	h := http.Header{}
 	h.Set("Authorization", "BEARER asdf")
-	if status, resp := testRequest(t, ts, "GET", "/", h, nil); status != 400 {
+	// HandlerSet issues new token, HandlerRead still rejects invalid one of request
+	if status, resp := testRequest(t, ts, "GET", "/", h, nil); status != 401 {
 		t.Fatal(resp)
 	}
 
@@ -73,3 +79,72 @@ func testRequest(t *testing.T, ts *httptest.Server, method, path string, header
 
 	return resp.StatusCode, string(respBody)
 }
+
+func TestJwt_NewUserToken(t *testing.T) {
+	j := NewJwt(storage.NewMemoryStore(), zap.L(), "123")
+
+	user, token, err := j.NewUserToken(context.Background())
+	require.NoError(t, err)
+
+	userID, err := j.UserIDFromToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+
+	_, err = j.UserIDFromToken("asdf")
+	require.Error(t, err)
+
+	assert.Equal(t, token, TokenFromBearer("Bearer "+token))
+	assert.Equal(t, "", TokenFromBearer(token))
+}
+
+func TestJwt_HandlerSet_InvalidToken(t *testing.T) {
+	j, err := NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, NewHMACKey("k1", "secret"))
+	require.NoError(t, err)
+
+	// same kid, key of instance before restart
+	restarted, err := NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, NewHMACKey("k1", "random"))
+	require.NoError(t, err)
+
+	unknown, err := NewJwtWithKeys(storage.NewMemoryStore(), zap.L(), time.Hour, NewHMACKey("k2", "secret"))
+	require.NoError(t, err)
+
+	restartedToken, err := restarted.buildJWTString(&domain.User{ID: 5})
+	require.NoError(t, err)
+
+	unknownToken, err := unknown.buildJWTString(&domain.User{ID: 6})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "malformed", token: "asdf"},
+		{name: "restarted", token: restartedToken},
+		{name: "unknown_kid", token: unknownToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userID int64
+			h := j.HandlerSet()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ = util.ReadUserIDFromCtx(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			issued := w.Header().Get("Authorization")
+			require.NotEmpty(t, issued)
+			assert.NotEqual(t, tt.token, issued)
+
+			id, err := j.UserIDFromToken(issued)
+			require.NoError(t, err)
+			assert.Equal(t, userID, id)
+		})
+	}
+}
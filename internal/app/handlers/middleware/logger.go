@@ -4,9 +4,19 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/logger"
 )
 
+// RequestIDHeader header of request ID, it is accepted from client or proxy and echoed in response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen max length of request ID accepted from client
+const maxRequestIDLen = 128
+
 type writer struct {
 	http.ResponseWriter
 	code        int
@@ -14,40 +24,42 @@ type writer struct {
 	wroteHeader bool
 }
 
-// LoggRequest - Сведения о запросах должны содержать:
-// URI, метод запроса и время, затраченное на его выполнение.
-func LoggRequest(logger *zap.Logger) func(next http.Handler) http.Handler {
+// AccessLog - middleware logging one line per request with method, route pattern, status,
+// size of response, duration, user and request ID.
+// Request ID is taken from X-Request-ID header or generated, request scoped logger carrying it
+// is put in context for handlers and stores, see logger.FromContext
+func AccessLog(l *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
-			defer func() {
-				duration := time.Since(start)
-				logger.Info(
-					"request",
-					zap.String("RequestURI", r.RequestURI),
-					zap.String("Method", r.Method),
-					zap.Duration("ElapsedTime", duration),
-				)
-			}()
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
 
-			next.ServeHTTP(w, r)
-		})
-	}
-}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := logger.WithRequestID(r.Context(), id)
+			ctx = logger.WithLogger(ctx, l.With(zap.String("request_id", id)))
+			r = r.WithContext(ctx)
 
-// LoggResponse - Сведения об ответах должны содержать:
-// код статуса и размер содержимого ответа.
-func LoggResponse(logger *zap.Logger) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			wr := &writer{ResponseWriter: w}
 
 			defer func() {
-				logger.Info(
-					"response",
-					zap.Int("StatusCode", wr.code),
-					zap.Int("BytesWritten", wr.bytes),
+				code := wr.code
+				if code == 0 {
+					code = http.StatusOK
+				}
+
+				logger.FromContext(ctx, l).Info(
+					"access",
+					zap.String("method", r.Method),
+					zap.String("route", routePattern(r)),
+					zap.Int("status", code),
+					zap.Int("bytes", wr.bytes),
+					zap.Duration("duration", time.Since(start)),
+					zap.String("remote_addr", ClientIP(r)),
 				)
 			}()
 
@@ -56,7 +68,33 @@ func LoggResponse(logger *zap.Logger) func(next http.Handler) http.Handler {
 	}
 }
 
-// WriteHeader jwt interceptor
+// routePattern matched route pattern of chi, path when route is not matched
+func routePattern(r *http.Request) string {
+	if rc := chi.RouteContext(r.Context()); rc != nil {
+		if p := rc.RoutePattern(); p != "" {
+			return p
+		}
+	}
+
+	return r.URL.Path
+}
+
+// validRequestID check request ID of client is short and contains only visible ASCII characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteHeader status code interceptor
 func (w *writer) WriteHeader(code int) {
 	if !w.wroteHeader {
 		w.code = code
@@ -65,10 +103,26 @@ func (w *writer) WriteHeader(code int) {
 	}
 }
 
-// Write jwt interceptor, writes number of bytes send out body
+// Write interceptor, writes number of bytes send out body
 func (w *writer) Write(buf []byte) (n int, err error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
 	n, err = w.ResponseWriter.Write(buf)
 	w.bytes += n
 
 	return n, err
 }
+
+// Flush sends buffered data to client
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap underlying writer for http.ResponseController
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -1,15 +1,20 @@
 package middleware
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
-	"net/http"
-	"net/http/httptest"
-	"testing"
+
+	"github.com/korol8484/shortener/internal/app/logger"
 )
 
 func TestWriter(t *testing.T) {
@@ -26,31 +31,95 @@ func TestWriter(t *testing.T) {
 	w.WriteHeader(1)
 }
 
-func TestLoggRequest(t *testing.T) {
-	withLogger(t, zapcore.DebugLevel, nil, func(logger *zap.Logger, logs *observer.ObservedLogs, t *testing.T) {
+func TestAccessLog(t *testing.T) {
+	withLogger(t, zapcore.DebugLevel, nil, func(l *zap.Logger, logs *observer.ObservedLogs, t *testing.T) {
 		r := chi.NewRouter()
-		r.Use(LoggRequest(logger), LoggResponse(logger))
+		r.Use(AccessLog(l))
+
+		r.Get("/get/{id}", func(w http.ResponseWriter, r *http.Request) {
+			logger.AddFields(r.Context(), zap.Int64("user_id", 5))
+			logger.FromContext(r.Context(), zap.NewNop()).Debug("handler")
 
-		r.Get("/get", func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
 			w.Write([]byte("htmlstring"))
 		})
 
 		ts := httptest.NewServer(r)
 		defer ts.Close()
 
-		req, err := http.NewRequest("GET", ts.URL+"/get", nil)
+		req, err := http.NewRequest("GET", ts.URL+"/get/1", nil)
 		require.NoError(t, err)
+		req.Header.Set(RequestIDHeader, "req-1")
 
 		resp, err := http.DefaultClient.Do(req)
 		require.NoError(t, err)
 
 		defer resp.Body.Close()
 
-		assert.Equal(t, logs.Len(), 2)
+		assert.Equal(t, "req-1", resp.Header.Get(RequestIDHeader))
+		require.Equal(t, 2, logs.Len())
+
+		handler := logs.All()[0]
+		assert.Equal(t, "handler", handler.Message)
+		assert.Equal(t, "req-1", handler.ContextMap()["request_id"])
+
+		access := logs.All()[1]
+		fields := access.ContextMap()
+		assert.Equal(t, "access", access.Message)
+		assert.Equal(t, "GET", fields["method"])
+		assert.Equal(t, "/get/{id}", fields["route"])
+		assert.Equal(t, int64(http.StatusAccepted), fields["status"])
+		assert.Equal(t, int64(len("htmlstring")), fields["bytes"])
+		assert.Equal(t, int64(5), fields["user_id"])
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Contains(t, fields, "duration")
 	})
 }
 
+func TestAccessLog_GenerateRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "absent", header: ""},
+		{name: "invalid", header: "bad id"},
+		{name: "too_long", header: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withLogger(t, zapcore.InfoLevel, nil, func(l *zap.Logger, logs *observer.ObservedLogs, t *testing.T) {
+				var ctxID string
+
+				h := AccessLog(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctxID, _ = logger.RequestIDFromContext(r.Context())
+				}))
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tt.header != "" {
+					req.Header.Set(RequestIDHeader, tt.header)
+				}
+
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+
+				id := w.Header().Get(RequestIDHeader)
+				_, err := uuid.Parse(id)
+				require.NoError(t, err)
+				assert.Equal(t, id, ctxID)
+
+				require.Equal(t, 1, logs.Len())
+				fields := logs.All()[0].ContextMap()
+				assert.Equal(t, id, fields["request_id"])
+				assert.Equal(t, "/", fields["route"])
+				assert.Equal(t, int64(http.StatusOK), fields["status"])
+				assert.NotContains(t, fields, "user_id")
+			})
+		})
+	}
+}
+
 func withLogger(t *testing.T, e zapcore.LevelEnabler, opts []zap.Option, f func(*zap.Logger, *observer.ObservedLogs, *testing.T)) {
 	fac, logs := observer.New(e)
 	log := zap.New(fac, opts...)
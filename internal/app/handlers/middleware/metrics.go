@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// unmatchedRoute route label of requests not matched by router, path is not used to keep labels bounded
+const unmatchedRoute = "unmatched"
+
+// HTTPObserver receiver of HTTP requests metrics
+type HTTPObserver interface {
+	ObserveHTTP(method, route string, status int, duration time.Duration)
+}
+
+// Instrument returns a middleware that observes status and latency of requests by chi route pattern
+func Instrument(o HTTPObserver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wr := &writer{ResponseWriter: w}
+
+			next.ServeHTTP(wr, r)
+
+			code := wr.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+
+			route := unmatchedRoute
+			if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+				route = rc.RoutePattern()
+			}
+
+			o.ObserveHTTP(method(r.Method), route, code, time.Since(start))
+		})
+	}
+}
+
+// method standard request method or OTHER to keep labels bounded
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return m
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type observed struct {
+	method string
+	route  string
+	status int
+}
+
+type httpObserver []observed
+
+func (o *httpObserver) ObserveHTTP(method, route string, status int, _ time.Duration) {
+	*o = append(*o, observed{method: method, route: route, status: status})
+}
+
+func TestInstrument(t *testing.T) {
+	o := &httpObserver{}
+
+	r := chi.NewRouter()
+	r.Use(Instrument(o))
+
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/abc", nil),
+		httptest.NewRequest(http.MethodPost, "/", nil),
+		httptest.NewRequest(http.MethodGet, "/a/b", nil),
+		httptest.NewRequest("PROPFIND", "/", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, httpObserver{
+		{method: http.MethodGet, route: "/{id}", status: http.StatusTemporaryRedirect},
+		{method: http.MethodPost, route: "/", status: http.StatusOK},
+		{method: http.MethodGet, route: unmatchedRoute, status: http.StatusNotFound},
+		{method: "OTHER", route: unmatchedRoute, status: http.StatusMethodNotAllowed},
+	}, *o)
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/logger"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// RateLimitConfig - rate limit config interface
+type RateLimitConfig interface {
+	GetRateLimitIP() string
+	GetRateLimitUser() string
+}
+
+// RateLimit middleware limiting requests to route by token buckets of client IP and user.
+// Client IP is read by ClientIP
+type RateLimit struct {
+	limiter ratelimit.Limiter
+	ip      ratelimit.Quota
+	user    ratelimit.Quota
+	logger  *zap.Logger
+}
+
+// NewRateLimit Factory, quotas are read from config
+func NewRateLimit(limiter ratelimit.Limiter, cfg RateLimitConfig, logger *zap.Logger) (*RateLimit, error) {
+	ip, err := ratelimit.ParseQuota(cfg.GetRateLimitIP())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ratelimit.ParseQuota(cfg.GetRateLimitUser())
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimit{limiter: limiter, ip: ip, user: user, logger: logger}, nil
+}
+
+// ByIP returns a middleware that limits requests to route per client IP,
+// it runs before user is set to limit creation of users too
+func (l *RateLimit) ByIP(route string) func(next http.Handler) http.Handler {
+	return l.handler(l.ip, func(r *http.Request) (string, bool) {
+		return "ip:" + route + ":" + ClientIP(r), true
+	})
+}
+
+// ByUser returns a middleware that limits requests to route per user set by Jwt middleware
+func (l *RateLimit) ByUser(route string) func(next http.Handler) http.Handler {
+	return l.handler(l.user, func(r *http.Request) (string, bool) {
+		userID, ok := util.ReadUserIDFromCtx(r.Context())
+		if !ok {
+			return "", false
+		}
+
+		return "user:" + route + ":" + strconv.FormatInt(userID, 10), true
+	})
+}
+
+func (l *RateLimit) handler(q ratelimit.Quota, key func(r *http.Request) (string, bool)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !q.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := l.limiter.Allow(r.Context(), k, q)
+			if err != nil {
+				// broken limiter must not stop service
+				logger.FromContext(r.Context(), l.logger).Error("can't check rate limit", zap.Error(err))
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			setRateLimitHeaders(w.Header(), q, res)
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				w.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRateLimitHeaders set RateLimit-* headers of IETF draft, when request passes several limits
+// headers describe the one with less remaining requests
+func setRateLimitHeaders(h http.Header, q ratelimit.Quota, res *ratelimit.Result) {
+	if prev, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err == nil && prev <= res.Remaining {
+		return
+	}
+
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+	h.Set("RateLimit-Policy", strconv.Itoa(q.Limit)+";w="+seconds(q.Period))
+}
+
+// seconds format duration as whole seconds rounded up
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+type brokenLimiter struct{}
+
+func (brokenLimiter) Allow(context.Context, string, ratelimit.Quota) (*ratelimit.Result, error) {
+	return nil, errors.New("broken")
+}
+
+func (brokenLimiter) Close() error {
+	return nil
+}
+
+func TestRateLimit(t *testing.T) {
+	l, err := NewRateLimit(ratelimit.NewMemory(), &config.App{RateLimitIP: "3/1m", RateLimitUser: "2/1m"}, zap.L())
+	require.NoError(t, err)
+
+	setUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), 1)))
+		})
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	r := chi.NewRouter()
+	r.With(l.ByIP("/a"), setUser, l.ByUser("/a")).Post("/a", ok)
+	r.With(l.ByIP("/b")).Post("/b", ok)
+
+	do := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":1234"
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := do("/a", "10.0.0.1")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	// headers of limit with less remaining requests
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	assert.Equal(t, http.StatusCreated, do("/a", "10.0.0.1").Code)
+
+	// user quota is exhausted, requests of another IP are limited by user too
+	w = do("/a", "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	// routes have separate buckets
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusCreated, do("/b", "10.0.0.1").Code)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, do("/b", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusCreated, do("/b", "10.0.0.3").Code)
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	_, err := NewRateLimit(ratelimit.NewMemory(), &config.App{RateLimitIP: "1"}, zap.L())
+	require.ErrorIs(t, err, ratelimit.ErrInvalidQuota)
+
+	// broken limiter and not limited quota let requests pass
+	l, err := NewRateLimit(brokenLimiter{}, &config.App{RateLimitIP: "1/1m"}, zap.L())
+	require.NoError(t, err)
+
+	h := l.ByIP("/")(l.ByUser("/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP resolver of client address, X-Real-IP header is honoured only from proxies inside trusted CIDR,
+// other clients can't spoof address by header
+type RealIP struct {
+	proxies []*net.IPNet
+}
+
+// NewRealIP Factory, cidrs comma separated CIDR of trusted proxies, empty - header is never honoured
+func NewRealIP(cidrs string) (*RealIP, error) {
+	rip := &RealIP{}
+
+	for _, v := range strings.Split(cidrs, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		_, subnet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse trusted proxy: %w", err)
+		}
+
+		rip.proxies = append(rip.proxies, subnet)
+	}
+
+	return rip, nil
+}
+
+// Resolve return address from header when remote address is trusted proxy, otherwise host of remote address
+func (rip *RealIP) Resolve(remoteAddr, header string) string {
+	host := hostOf(remoteAddr)
+	if header == "" || !rip.trusted(host) {
+		return host
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(header))
+	if ip == nil {
+		return host
+	}
+
+	return ip.String()
+}
+
+// Handler returns a middleware that replaces remote address of request by resolved client address,
+// handlers after it read client address by ClientIP
+func (rip *RealIP) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := rip.Resolve(r.RemoteAddr, r.Header.Get("X-Real-IP")); ip != hostOf(r.RemoteAddr) {
+			r.RemoteAddr = net.JoinHostPort(ip, "0")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rip *RealIP) trusted(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, subnet := range rip.proxies {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP return client address of request, address from trusted proxy header is set by RealIP
+func ClientIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
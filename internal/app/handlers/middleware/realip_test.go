@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealIP(t *testing.T) {
+	_, err := NewRealIP("10.0.0.0/33")
+	require.Error(t, err)
+
+	rip, err := NewRealIP("10.0.0.0/8, 192.168.1.1/32")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		remote string
+		header string
+		want   string
+	}{
+		{name: "trusted_proxy", remote: "10.1.2.3:5000", header: "8.8.8.8", want: "8.8.8.8"},
+		{name: "second_proxy", remote: "192.168.1.1:5000", header: "8.8.4.4", want: "8.8.4.4"},
+		{name: "spoofed", remote: "1.1.1.1:5000", header: "8.8.8.8", want: "1.1.1.1"},
+		{name: "no_header", remote: "10.1.2.3:5000", header: "", want: "10.1.2.3"},
+		{name: "bad_header", remote: "10.1.2.3:5000", header: "not-ip", want: "10.1.2.3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+
+			h := rip.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = test.remote
+			r.Header.Set("X-Real-IP", test.header)
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			assert.Equal(t, test.want, got)
+		})
+	}
+
+	// without trusted proxies header is ignored
+	rip, err = NewRealIP("")
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", rip.Resolve("10.1.2.3:5000", "8.8.8.8"))
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/logger"
+)
+
+// tracerScope instrumentation scope of HTTP spans
+const tracerScope = "github.com/korol8484/shortener/internal/app/handlers/middleware"
+
+type ctxKey string
+
+// keyParentSpan - key to set\read span continued after traced middleware
+const keyParentSpan ctxKey = "parent_span"
+
+// Trace returns a middleware that starts server span of request named by chi route pattern,
+// trace context of caller is extracted from request headers by global propagator.
+// Trace ID is added to request scoped logger
+func Trace() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := otel.Tracer(tracerScope).Start(
+				ctx,
+				r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("client.address", ClientIP(r)),
+				),
+			)
+			defer span.End()
+
+			if sc := span.SpanContext(); sc.IsValid() {
+				logger.AddFields(ctx, zap.String("trace_id", sc.TraceID().String()))
+			}
+
+			wr := &writer{ResponseWriter: w}
+			next.ServeHTTP(wr, r.WithContext(ctx))
+
+			code := wr.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+
+			route := unmatchedRoute
+			if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+				route = rc.RoutePattern()
+			}
+
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", code),
+			)
+
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(code))
+			}
+		})
+	}
+}
+
+// TraceMiddleware wrap middleware in span of given name, span ends when middleware calls next handler
+// or returns, so time of middleware is seen apart from handlers after it
+func TraceMiddleware(name string, mw func(next http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			trace.SpanFromContext(ctx).End()
+
+			// handlers after middleware continue parent span
+			if parent, ok := ctx.Value(keyParentSpan).(trace.Span); ok {
+				ctx = trace.ContextWithSpan(ctx, parent)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), keyParentSpan, trace.SpanFromContext(r.Context()))
+
+			ctx, span := otel.Tracer(tracerScope).Start(ctx, name)
+			// ending of span ended by next handler is ignored
+			defer span.End()
+
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+// withRecorder install global provider recording spans and W3C propagator for test
+func withRecorder(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	return sr
+}
+
+func TestTrace(t *testing.T) {
+	sr := withRecorder(t)
+
+	withLogger(t, zapcore.InfoLevel, nil, func(l *zap.Logger, logs *observer.ObservedLogs, t *testing.T) {
+		r := chi.NewRouter()
+		r.Use(AccessLog(l), Trace())
+
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		})
+		r.Post("/fail", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fail", nil))
+
+		spans := sr.Ended()
+		require.Len(t, spans, 2)
+
+		span := spans[0]
+		assert.Equal(t, "GET /{id}", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.Contains(t, span.Attributes(), attribute.String("http.route", "/{id}"))
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusTemporaryRedirect))
+
+		assert.Equal(t, "POST /fail", spans[1].Name())
+		assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+		require.Equal(t, 2, logs.Len())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logs.All()[0].ContextMap()["trace_id"])
+	})
+}
+
+func TestTraceMiddleware(t *testing.T) {
+	sr := withRecorder(t)
+
+	j := NewJwt(storage.NewMemoryStore(), zap.L(), "123")
+
+	var handlerParent trace.SpanContext
+
+	r := chi.NewRouter()
+	r.Use(Trace(), TraceMiddleware("jwt.set", j.HandlerSet()))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		handlerParent = trace.SpanContextFromContext(r.Context())
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+
+	mw, server := spans[0], spans[1]
+	assert.Equal(t, "jwt.set", mw.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), mw.Parent().SpanID())
+	// handler continues server span, not ended span of middleware
+	assert.Equal(t, server.SpanContext().SpanID(), handlerParent.SpanID())
+	assert.False(t, mw.EndTime().After(server.EndTime()))
+}
+
+func TestTraceMiddleware_Rejected(t *testing.T) {
+	sr := withRecorder(t)
+
+	j := NewJwt(storage.NewMemoryStore(), zap.L(), "123")
+
+	r := chi.NewRouter()
+	r.Use(Trace())
+	// inline middleware runs after route is matched as in router of service
+	r.With(TraceMiddleware("jwt.read", j.HandlerRead())).Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "jwt.read", spans[0].Name())
+	assert.Equal(t, "GET /", spans[1].Name())
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// TrustedSubnet returns a middleware that allows requests only from client IP inside CIDR,
+// client IP is read by ClientIP, so X-Real-IP header is honoured only from proxies trusted by RealIP.
+// Empty CIDR forbids all requests
+func TrustedSubnet(cidr string) (func(next http.Handler) http.Handler, error) {
+	var subnet *net.IPNet
+
+	if cidr != "" {
+		var err error
+
+		_, subnet, err = net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse trusted subnet: %w", err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(ClientIP(r))
+			if subnet == nil || ip == nil || !subnet.Contains(ip) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rip, err := NewRealIP("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		cidr   string
+		remote string
+		ip     string
+		want   int
+	}{
+		{name: "inside", cidr: "192.168.1.0/24", remote: "192.168.1.10:5000", want: http.StatusOK},
+		{name: "outside", cidr: "192.168.1.0/24", remote: "172.16.0.1:5000", want: http.StatusForbidden},
+		{name: "trusted_proxy", cidr: "192.168.1.0/24", remote: "10.0.0.1:5000", ip: "192.168.1.10", want: http.StatusOK},
+		{name: "spoofed", cidr: "192.168.1.0/24", remote: "172.16.0.1:5000", ip: "192.168.1.10", want: http.StatusForbidden},
+		{name: "not_configured", cidr: "", remote: "192.168.1.10:5000", want: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := TrustedSubnet(test.cidr)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			r.RemoteAddr = test.remote
+			if test.ip != "" {
+				r.Header.Set("X-Real-IP", test.ip)
+			}
+
+			w := httptest.NewRecorder()
+			rip.Handler(m(ok)).ServeHTTP(w, r)
+
+			assert.Equal(t, test.want, w.Code)
+		})
+	}
+
+	_, err = TrustedSubnet("192.168.1.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+const (
+	// defaultPageLimit count of user links in page when limit is not requested
+	defaultPageLimit = 100
+	// maxPageLimit max count of user links in page
+	maxPageLimit = 1000
+)
+
+var (
+	// ErrInvalidCursor - Ошибка что курсор страницы не может быть прочитан
+	ErrInvalidCursor = errors.New("invalid page cursor")
+	// ErrInvalidLimit - Ошибка что размер страницы задан неверно
+	ErrInvalidLimit = errors.New("invalid page limit")
+)
+
+type cursorToken struct {
+	CreatedAt time.Time `json:"t"`
+	Alias     string    `json:"a"`
+}
+
+// EncodeCursor encode cursor to opaque string
+func EncodeCursor(c *domain.Cursor) string {
+	b, _ := json.Marshal(&cursorToken{CreatedAt: c.CreatedAt, Alias: c.Alias})
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decode opaque cursor returned by EncodeCursor
+func DecodeCursor(s string) (*domain.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var t cursorToken
+	if err = json.Unmarshal(b, &t); err != nil || t.Alias == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &domain.Cursor{CreatedAt: t.CreatedAt, Alias: t.Alias}, nil
+}
+
+// NewUserURLQuery build page request of user links, limit 0 - default limit, empty cursor - first page
+func NewUserURLQuery(limit int, cursor string, desc bool, contains string, includeDeleted bool) (*domain.UserURLQuery, error) {
+	if limit < 0 || limit > maxPageLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	q := &domain.UserURLQuery{
+		Limit:          limit,
+		Desc:           desc,
+		Contains:       contains,
+		IncludeDeleted: includeDeleted,
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultPageLimit
+	}
+
+	if cursor != "" {
+		after, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		q.After = after
+	}
+
+	return q, nil
+}
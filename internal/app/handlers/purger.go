@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Purgeable store which can physically remove links deleted before given time
+type Purgeable interface {
+	Purge(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// PurgeListener removes data kept by aliases of purged links
+type PurgeListener interface {
+	Purged(ctx context.Context, aliases []string) error
+}
+
+// Purger periodically removes links deleted longer than retention ago, listeners are notified about purged aliases
+type Purger struct {
+	store     Purgeable
+	listeners []PurgeListener
+	retention time.Duration
+	interval  time.Duration
+	closeChan chan struct{}
+	doneChan  chan struct{}
+	logger    *zap.Logger
+}
+
+// NewPurger Factory, starts background worker
+func NewPurger(
+	store Purgeable,
+	retention, interval time.Duration,
+	logger *zap.Logger,
+	listeners ...PurgeListener,
+) (*Purger, error) {
+	p := &Purger{
+		store:     store,
+		listeners: listeners,
+		retention: retention,
+		interval:  interval,
+		closeChan: make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		logger:    logger,
+	}
+
+	go p.process()
+
+	return p, nil
+}
+
+// Close - stop background worker
+func (p *Purger) Close() {
+	close(p.closeChan)
+	<-p.doneChan
+}
+
+func (p *Purger) process() {
+	defer close(p.doneChan)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			purged, err := p.store.Purge(context.Background(), now.Add(-p.retention))
+			if err != nil {
+				p.logger.Error("can't purge deleted links", zap.Error(err))
+				continue
+			}
+
+			if len(purged) == 0 {
+				continue
+			}
+
+			p.logger.Info("deleted links purged", zap.Int("count", len(purged)))
+
+			for _, l := range p.listeners {
+				if err = l.Purged(context.Background(), purged); err != nil {
+					p.logger.Error("can't remove data of purged links", zap.Error(err))
+				}
+			}
+		case <-p.closeChan:
+			p.logger.Info("close purger worker")
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	clickStorage "github.com/korol8484/shortener/internal/app/click/storage"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+)
+
+func TestPurger(t *testing.T) {
+	store := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+	require.NoError(t, err)
+
+	clickStore := clickStorage.NewMemoryStore()
+	require.NoError(t, clickStore.AddClicks(context.Background(), []*domain.Click{{Alias: "7A2S4z", At: time.Now()}}))
+
+	clicks, err := NewClicks(clickStore, store, zap.L())
+	require.NoError(t, err)
+	defer clicks.Close()
+
+	p, err := NewPurger(store, 10*time.Millisecond, 5*time.Millisecond, zap.L(), clicks)
+	require.NoError(t, err)
+	defer p.Close()
+
+	assert.Eventually(t, func() bool {
+		_, rErr := store.Read(context.Background(), "7A2S4z")
+		return errors.Is(rErr, storage.ErrNotFound)
+	}, time.Second, 5*time.Millisecond)
+
+	// clicks of purged link are removed
+	assert.Eventually(t, func() bool {
+		stats, sErr := clickStore.Stats(context.Background(), "7A2S4z")
+		require.NoError(t, sErr)
+
+		return stats.Total == 0
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Expirable store which can soft delete expired links
+type Expirable interface {
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Reaper periodically soft deletes expired links
+type Reaper struct {
+	store     Expirable
+	interval  time.Duration
+	closeChan chan struct{}
+	doneChan  chan struct{}
+	logger    *zap.Logger
+}
+
+// NewReaper Factory, starts background worker
+func NewReaper(store Expirable, interval time.Duration, logger *zap.Logger) (*Reaper, error) {
+	r := &Reaper{
+		store:     store,
+		interval:  interval,
+		closeChan: make(chan struct{}),
+		doneChan:  make(chan struct{}),
+		logger:    logger,
+	}
+
+	go r.process()
+
+	return r, nil
+}
+
+// Close - stop background worker
+func (r *Reaper) Close() {
+	close(r.closeChan)
+	<-r.doneChan
+}
+
+func (r *Reaper) process() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			n, err := r.store.DeleteExpired(context.Background(), now)
+			if err != nil {
+				r.logger.Error("can't delete expired links", zap.Error(err))
+				continue
+			}
+
+			if n > 0 {
+				r.logger.Info("expired links deleted", zap.Int64("count", n))
+			}
+		case <-r.closeChan:
+			r.logger.Info("close reaper worker")
+			return
+		}
+	}
+}
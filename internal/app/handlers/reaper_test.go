@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+)
+
+func TestReaper(t *testing.T) {
+	store := memory.NewMemStore()
+
+	err := store.Add(context.Background(), &domain.URL{
+		URL:       "http://www.ya.ru",
+		Alias:     "7A2S4z",
+		ExpiresAt: time.Now().Add(10 * time.Millisecond),
+	}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	r, err := NewReaper(store, 5*time.Millisecond, zap.L())
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Eventually(t, func() bool {
+		ent, rErr := store.Read(context.Background(), "7A2S4z")
+		return rErr == nil && ent.Deleted
+	}, time.Second, 5*time.Millisecond)
+}
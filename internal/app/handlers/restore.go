@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+type restoreResponse struct {
+	Restored []string `json:"restored"`
+}
+
+// RestoreURLs un-delete user shorten URL deleted within retention, 0 retention - any time.
+// Returns restored aliases
+func RestoreURLs(ctx context.Context, store Store, aliases []string, userID int64, retention time.Duration) ([]string, error) {
+	var since time.Time
+	if retention > 0 {
+		since = time.Now().Add(-retention)
+	}
+
+	return store.Restore(ctx, unique(aliases), userID, since)
+}
+
+// Restore Handler for un-delete collection of user shorten URLs
+// Accepts input json:
+// ["cbi7jn", "dyifOs"]
+// Returns aliases restored, links of another user, not deleted, expired
+// or deleted before retention window are skipped:
+//
+//	{
+//	    "restored": ["cbi7jn"]
+//	}
+func (a *API) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "can't read request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	var aliases []string
+	if err = json.Unmarshal(body, &aliases); err != nil {
+		http.Error(w, "can't parse json", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := RestoreURLs(r.Context(), a.store, aliases, userID, a.cfg.GetDeleteRetention())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if restored == nil {
+		restored = []string{}
+	}
+
+	b, err := json.Marshal(&restoreResponse{Restored: restored})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
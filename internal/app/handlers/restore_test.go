@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+func TestAPI_Restore(t *testing.T) {
+	store := memory.NewMemStore()
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+
+	_, err := store.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+	_, err = store.BatchDeleteAt(ctx, []string{"b"}, user.ID, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+
+	api := NewAPI(store, &config.App{BaseShortURL: "http://localhost", DeleteRetention: time.Hour}, alias.NewHash(alias.DefaultLength))
+
+	restore := func(body string, userID int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/urls/restore", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		api.Restore(w, r.WithContext(util.SetUserIDToCtx(r.Context(), userID)))
+
+		return w
+	}
+
+	w := restore(`["a"]`, 2)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"restored":[]}`, w.Body.String())
+
+	// b is deleted before retention window
+	w = restore(`["a", "b", "a"]`, user.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp restoreResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"a"}, resp.Restored)
+
+	ent, err := store.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+
+	w = restore(`{`, user.ID)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -6,10 +6,12 @@ import (
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 )
 
-// CreateRouter create HTTP router, register endpoints using chi
+// CreateRouter create HTTP router, register endpoints using chi, compaction endpoint is registered
+// only with not nil compact
 // @title			Shortener API
 // @version		1.0
 // @description	Golang service
@@ -25,30 +27,52 @@ func CreateRouter(
 	cfg Config,
 	logger *zap.Logger,
 	p Pingable,
-	userRep middleware.UserAddRepository,
+	jwtH *middleware.Jwt,
+	compressor *middleware.Compressor,
+	rateLimit *middleware.RateLimit,
 	deleteHandler *Delete,
+	gen alias.Generator,
+	clicks *Clicks,
+	users UserCounter,
+	trustedSubnet func(next http.Handler) http.Handler,
+	instrument func(next http.Handler) http.Handler,
+	compact Compactable,
 ) http.Handler {
-	api := NewAPI(store, cfg)
+	api := NewAPI(store, cfg, gen)
 	r := chi.NewRouter()
+	r.Use(middleware.AccessLog(logger), instrument, middleware.Trace())
+
+	// time of token checks and user creation is traced apart from handlers
+	jwtSet := middleware.TraceMiddleware("jwt.set", jwtH.HandlerSet())
+	jwtRead := middleware.TraceMiddleware("jwt.read", jwtH.HandlerRead())
 
 	r.Group(func(r chi.Router) {
-		r.Use(
-			middleware.LoggResponse(logger),
-			middleware.LoggRequest(logger),
-			middleware.NewCompressor().Handler,
-		)
-
-		jwtH := middleware.NewJwt(userRep, logger, "12345dsdsdtoken")
-
-		r.With(jwtH.HandlerSet()).Post("/", api.HandleShort)
-		r.Get("/{id}", api.HandleRedirect)
-		r.With(jwtH.HandlerSet()).Post("/api/shorten", api.ShortenJSON)
-		r.With(jwtH.HandlerSet()).Post("/api/shorten/batch", api.ShortenBatch)
-		r.With(jwtH.HandlerRead()).Get("/api/user/urls", api.UserURL)
-		r.With(jwtH.HandlerRead()).Delete("/api/user/urls", deleteHandler.BatchDelete)
+		r.Use(compressor.Handler)
+
+		// shorten endpoints create users and links, they are limited by client IP before user is set and by user after
+		shorten := func(route string) chi.Router {
+			return r.With(rateLimit.ByIP(route), jwtSet, rateLimit.ByUser(route))
+		}
+
+		shorten("/").Post("/", api.HandleShort)
+		r.With(clicks.Track).Get("/{id}", api.HandleRedirect)
+		shorten("/api/shorten").Post("/api/shorten", api.ShortenJSON)
+		shorten("/api/shorten/batch").Post("/api/shorten/batch", api.ShortenBatch)
+		r.With(jwtRead).Get("/api/user/urls", api.UserURL)
+		r.With(jwtRead).Delete("/api/user/urls", deleteHandler.BatchDelete)
+		r.With(jwtRead).Get(deleteJobPath+"{id}", deleteHandler.Job)
+		r.With(jwtRead).Post("/api/user/urls/restore", api.Restore)
+		r.With(jwtRead).Get("/api/user/urls/{alias}/stats", clicks.Stats)
+		r.With(jwtRead).Patch("/api/user/urls/{alias}", api.UpdateJSON)
+		r.With(jwtRead).Get("/api/user/urls/{alias}/revisions", api.Revisions)
 	})
 
 	r.Get("/ping", Ping(p))
+	r.With(trustedSubnet).Get("/api/internal/stats", InternalStats(store, users))
+
+	if compact != nil {
+		r.With(trustedSubnet).Post("/api/admin/compact", Compact(compact))
+	}
 
 	return r
 }
@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"github.com/korol8484/shortener/internal/app/alias"
+	clickStorage "github.com/korol8484/shortener/internal/app/click/storage"
 	"github.com/korol8484/shortener/internal/app/config"
+	deleteStorage "github.com/korol8484/shortener/internal/app/delete/storage"
+	"github.com/korol8484/shortener/internal/app/handlers/middleware"
+	"github.com/korol8484/shortener/internal/app/ratelimit"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
 	"github.com/korol8484/shortener/internal/app/user/storage"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -14,13 +22,44 @@ func TestCreateRouter(t *testing.T) {
 	cfg := &config.App{}
 	pi := &PingDummy{}
 	uRep := storage.NewMemoryStore()
+	jwtH := middleware.NewJwt(uRep, zap.L(), "123")
 
-	api, err := NewDelete(store, zap.L())
+	trusted, err := middleware.TrustedSubnet("192.168.1.0/24")
+	require.NoError(t, err)
+
+	api, err := NewDelete(store, deleteStorage.NewMemoryStore(), zap.L())
 	require.NoError(t, err)
 	defer api.Close()
 
-	r := CreateRouter(store, cfg, zap.L(), pi, uRep, api)
-	if r == nil {
-		t.Fatal("not implement http.Handler")
+	clicks, err := NewClicks(clickStorage.NewMemoryStore(), store, zap.L())
+	require.NoError(t, err)
+	defer clicks.Close()
+
+	rateLimit, err := middleware.NewRateLimit(ratelimit.NewMemory(), cfg, zap.L())
+	require.NoError(t, err)
+
+	instrument := func(next http.Handler) http.Handler {
+		return next
 	}
+
+	compact := func(r http.Handler, ip string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/compact", nil)
+		req.RemoteAddr = ip + ":5000"
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	// store without compaction
+	r := CreateRouter(store, cfg, zap.L(), pi, jwtH, middleware.NewCompressor(cfg), rateLimit, api, alias.NewHash(alias.DefaultLength), clicks, uRep, trusted, instrument, nil)
+	require.NotNil(t, r)
+	assert.Equal(t, http.StatusNotFound, compact(r, "192.168.1.10"))
+
+	r = CreateRouter(store, cfg, zap.L(), pi, jwtH, middleware.NewCompressor(cfg), rateLimit, api, alias.NewHash(alias.DefaultLength), clicks, uRep, trusted, instrument, compactFunc(func() error {
+		return nil
+	}))
+	assert.Equal(t, http.StatusForbidden, compact(r, "10.0.0.1"))
+	assert.Equal(t, http.StatusNoContent, compact(r, "192.168.1.10"))
 }
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+// maxAliasAttempts attempts to generate free alias before giving up
+const maxAliasAttempts = 10
+
+var (
+	// ErrAliasAttempts - Ошибка что не удалось сгенерировать свободный alias
+	ErrAliasAttempts = errors.New("can't generate free alias")
+	// ErrInvalidExpiry - Ошибка что срок жизни ссылки задан неверно
+	ErrInvalidExpiry = errors.New("invalid link expiry")
+)
+
+// ShortenURL save URL, alias of request is optional custom alias.
+// Without custom alias generates new alias while it is taken, custom alias taken returns storage.ErrAliasTaken.
+// For URL saved before returns saved entity and storage.ErrIssetURL
+func ShortenURL(
+	ctx context.Context,
+	store Store,
+	gen alias.Generator,
+	req *domain.URL,
+	user *domain.User,
+) (*domain.URL, error) {
+	ent, err := newEntity(req)
+	if err != nil {
+		return nil, err
+	}
+
+	custom := ent.Alias != ""
+
+	for attempt := 0; attempt < maxAliasAttempts; attempt++ {
+		if !custom {
+			ent.Alias, err = gen.Generate(ctx, req.URL, attempt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = store.Add(ctx, ent, user)
+		switch {
+		case err == nil:
+			return ent, nil
+		case errors.Is(err, storage.ErrAliasTaken) && !custom:
+			continue
+		case errors.Is(err, storage.ErrIssetURL):
+			var isset *domain.URL
+
+			isset, err = store.ReadByURL(ctx, ent.URL)
+			if err != nil {
+				return nil, err
+			}
+
+			return isset, storage.ErrIssetURL
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, ErrAliasAttempts
+}
+
+// ShortenURLBatch save collection URL atomically, items without custom alias get generated aliases,
+// which are generated again while one of them is taken. Outcomes returned in order of request,
+// URL shortened before returns existing link with domain.BatchItemExisting status
+func ShortenURLBatch(
+	ctx context.Context,
+	store Store,
+	gen alias.Generator,
+	req domain.BatchURL,
+	user *domain.User,
+) ([]*domain.BatchResult, error) {
+	batch := make(domain.BatchURL, 0, len(req))
+	custom := make(map[string]struct{})
+
+	for _, v := range req {
+		ent, err := newEntity(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if ent.Alias != "" {
+			if _, ok := custom[ent.Alias]; ok {
+				return nil, fmt.Errorf("%w: %s", storage.ErrAliasTaken, ent.Alias)
+			}
+
+			custom[ent.Alias] = struct{}{}
+		}
+
+		batch = append(batch, ent)
+	}
+
+	for attempt := 0; attempt < maxAliasAttempts; attempt++ {
+		for i, v := range batch {
+			if req[i].Alias != "" {
+				continue
+			}
+
+			a, err := gen.Generate(ctx, req[i].URL, attempt)
+			if err != nil {
+				return nil, err
+			}
+
+			v.Alias = a
+		}
+
+		results, err := store.AddBatch(ctx, batch, user)
+		switch {
+		case err == nil:
+			return results, nil
+		case errors.Is(err, storage.ErrAliasTaken):
+			// custom alias won't change on next attempt, alias of the same URL is not a conflict
+			for i, v := range batch {
+				if req[i].Alias == "" {
+					continue
+				}
+
+				if u, rErr := store.Read(ctx, v.Alias); rErr == nil && u.URL != v.URL {
+					return nil, fmt.Errorf("%w: %s", storage.ErrAliasTaken, v.Alias)
+				}
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, ErrAliasAttempts
+}
+
+// newEntity parse requested URL and validate custom alias
+func newEntity(req *domain.URL) (*domain.URL, error) {
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Alias != "" {
+		if err = alias.Validate(req.Alias); err != nil {
+			return nil, err
+		}
+	}
+
+	return &domain.URL{URL: parsedURL.String(), Alias: req.Alias, ExpiresAt: req.ExpiresAt}, nil
+}
+
+// ParseExpiry return link expiration time from absolute expiresAt or ttl duration like "72h",
+// zero time when both are empty
+func ParseExpiry(expiresAt *time.Time, ttl string, now time.Time) (time.Time, error) {
+	switch {
+	case expiresAt != nil && ttl != "":
+		return time.Time{}, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", ErrInvalidExpiry)
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return time.Time{}, fmt.Errorf("%w: expires_at must be in future", ErrInvalidExpiry)
+		}
+
+		return *expiresAt, nil
+	case ttl != "":
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("%w: ttl must be positive duration like 72h", ErrInvalidExpiry)
+		}
+
+		return now.Add(d), nil
+	default:
+		return time.Time{}, nil
+	}
+}
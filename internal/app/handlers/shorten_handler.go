@@ -6,25 +6,37 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/storage"
 	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
 type request struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
 }
 
 type response struct {
 	Result string `json:"result"`
 }
 
-// ShortenJSON Handler for json shortened link
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ShortenJSON Handler for json shortened link, alias is optional custom alias,
+// link expiration is optional absolute expires_at in RFC 3339 or ttl duration
 // Accepts input json:
 //
 //	{
-//	    "url": "http://www.ya.ru"
+//	    "url": "http://www.ya.ru",
+//	    "alias": "spring-sale",
+//	    "ttl": "72h"
 //	}
 //
 // Returns:
@@ -32,6 +44,12 @@ type response struct {
 //	{
 //	    "result": "http://localhost:8080/ZyNJrg"
 //	}
+//
+// Returns 409 with error body when custom alias is taken:
+//
+//	{
+//	    "error": "requested alias taken"
+//	}
 func (a *API) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -51,19 +69,26 @@ func (a *API) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ent, err := a.shortURL(req.URL)
-	if err != nil {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	userID, ok := util.ReadUserIDFromCtx(r.Context())
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
+	expiresAt, err := ParseExpiry(req.ExpiresAt, req.TTL, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if err = a.store.Add(r.Context(), ent, &domain.User{ID: userID}); err != nil {
+	ent, err := ShortenURL(
+		r.Context(),
+		a.store,
+		a.gen,
+		&domain.URL{URL: req.URL, Alias: req.Alias, ExpiresAt: expiresAt},
+		&domain.User{ID: userID},
+	)
+	if err != nil {
 		if errors.Is(err, storage.ErrIssetURL) {
 			res := &response{Result: fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), ent.Alias)}
 
@@ -81,6 +106,16 @@ func (a *API) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if errors.Is(err, storage.ErrAliasTaken) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+
+		if alias.IsInvalid(err) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -97,3 +132,16 @@ func (a *API) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	_, _ = w.Write(b)
 }
+
+// writeError write json error body with status code
+func writeError(w http.ResponseWriter, code int, err error) {
+	b, mErr := json.Marshal(&errorResponse{Error: err.Error()})
+	if mErr != nil {
+		w.WriteHeader(code)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(code)
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+)
+
+// takenGen returns same alias for attempts before free
+type takenGen struct {
+	free int
+}
+
+func (g takenGen) Generate(_ context.Context, _ string, attempt int) (string, error) {
+	if attempt < g.free {
+		return "taken", nil
+	}
+
+	return alias.EncodeBase62(int64(attempt), alias.DefaultLength), nil
+}
+
+func TestShortenURL(t *testing.T) {
+	store := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "taken"}, user)
+	require.NoError(t, err)
+
+	ent, err := ShortenURL(context.Background(), store, takenGen{free: 2}, &domain.URL{URL: "http://www.ya.ru"}, user)
+	require.NoError(t, err)
+	assert.Equal(t, alias.EncodeBase62(2, alias.DefaultLength), ent.Alias)
+
+	isset, err := ShortenURL(context.Background(), store, alias.NewHash(alias.DefaultLength), &domain.URL{URL: "http://www.ya.ru"}, user)
+	require.ErrorIs(t, err, storage.ErrIssetURL)
+	assert.Equal(t, ent.Alias, isset.Alias)
+
+	_, err = ShortenURL(context.Background(), store, takenGen{free: maxAliasAttempts}, &domain.URL{URL: "http://www.ya2.ru"}, user)
+	require.ErrorIs(t, err, ErrAliasAttempts)
+
+	_, err = ShortenURL(context.Background(), store, takenGen{}, &domain.URL{URL: "http__://www.ya.ru"}, user)
+	require.Error(t, err)
+
+	ent, err = ShortenURL(context.Background(), store, takenGen{}, &domain.URL{URL: "http://www.ya3.ru", Alias: "spring-sale"}, user)
+	require.NoError(t, err)
+	assert.Equal(t, "spring-sale", ent.Alias)
+
+	_, err = ShortenURL(context.Background(), store, takenGen{}, &domain.URL{URL: "http://www.ya4.ru", Alias: "spring-sale"}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = ShortenURL(context.Background(), store, takenGen{}, &domain.URL{URL: "http://www.ya4.ru", Alias: "api"}, user)
+	require.ErrorIs(t, err, alias.ErrReservedAlias)
+}
+
+func TestShortenURLBatch(t *testing.T) {
+	store := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "taken"}, user)
+	require.NoError(t, err)
+
+	batch, err := ShortenURLBatch(context.Background(), store, takenGen{free: 1}, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru"},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "spring-sale"},
+	}, user)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, alias.EncodeBase62(1, alias.DefaultLength), batch[0].URL.Alias)
+	assert.Equal(t, "spring-sale", batch[1].URL.Alias)
+
+	// URL shortened before returns existing link, retried custom alias of the same URL is not a conflict
+	batch, err = ShortenURLBatch(context.Background(), store, takenGen{free: 2}, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya1.ru"},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "spring-sale"},
+		&domain.URL{URL: "http://www.ya5.ru"},
+	}, user)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BatchItemExisting, batch[0].Status)
+	assert.Equal(t, "taken", batch[0].URL.Alias)
+	assert.Equal(t, domain.BatchItemExisting, batch[1].Status)
+	assert.Equal(t, domain.BatchItemCreated, batch[2].Status)
+
+	_, err = ShortenURLBatch(context.Background(), store, takenGen{}, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya3.ru"},
+		&domain.URL{URL: "http://www.ya4.ru", Alias: "spring-sale"},
+	}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = ShortenURLBatch(context.Background(), store, takenGen{}, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya3.ru", Alias: "summer"},
+		&domain.URL{URL: "http://www.ya4.ru", Alias: "summer"},
+	}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = ShortenURLBatch(context.Background(), store, takenGen{}, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya3.ru", Alias: "s"},
+	}, user)
+	require.ErrorIs(t, err, alias.ErrInvalidAlias)
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	expiresAt, err := ParseExpiry(nil, "", now)
+	require.NoError(t, err)
+	assert.True(t, expiresAt.IsZero())
+
+	expiresAt, err = ParseExpiry(&future, "", now)
+	require.NoError(t, err)
+	assert.Equal(t, future, expiresAt)
+
+	expiresAt, err = ParseExpiry(nil, "72h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(72*time.Hour), expiresAt)
+
+	_, err = ParseExpiry(&past, "", now)
+	require.ErrorIs(t, err, ErrInvalidExpiry)
+
+	_, err = ParseExpiry(&future, "72h", now)
+	require.ErrorIs(t, err, ErrInvalidExpiry)
+
+	_, err = ParseExpiry(nil, "-1h", now)
+	require.ErrorIs(t, err, ErrInvalidExpiry)
+
+	_, err = ParseExpiry(nil, "week", now)
+	require.ErrorIs(t, err, ErrInvalidExpiry)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+// ErrInvalidURL - Ошибка что новый URL ссылки задан неверно
+var ErrInvalidURL = errors.New("invalid url")
+
+type updateRequest struct {
+	URL string `json:"url"`
+}
+
+type revisionResponse struct {
+	URL       string    `json:"original_url"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// UpdateURL change destination of user shorten URL, previous destination is saved as revision
+func UpdateURL(ctx context.Context, store Store, alias, rawURL string, user *domain.User) (*domain.URL, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		return nil, ErrInvalidURL
+	}
+
+	ent := &domain.URL{URL: parsedURL.String(), Alias: alias}
+	if err = store.Update(ctx, ent, user); err != nil {
+		return nil, err
+	}
+
+	return ent, nil
+}
+
+// UpdateJSON Handler for change destination of user shorten URL
+// Accepts input json:
+//
+//	{
+//	    "url": "http://www.ya.ru/new"
+//	}
+//
+// Returns:
+//
+//	{
+//	    "short_url": "http://localhost:8080/ZyNJrg",
+//	    "original_url": "http://www.ya.ru/new"
+//	}
+//
+// Returns 404 when link is not owned by user or deleted, 409 when new URL is shortened by another link
+func (a *API) UpdateJSON(w http.ResponseWriter, r *http.Request) {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &updateRequest{}
+	if err = json.Unmarshal(body, req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ent, err := UpdateURL(r.Context(), a.store, chi.URLParam(r, "alias"), req.URL, &domain.User{ID: userID})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidURL):
+			writeError(w, http.StatusBadRequest, err)
+		case errors.Is(err, storage.ErrNotFound):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, storage.ErrIssetURL):
+			writeError(w, http.StatusConflict, err)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	b, err := json.Marshal(&responseURL{
+		URL:   fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), ent.Alias),
+		Alias: ent.URL,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
+// Revisions Handler for previous destinations of user shorten URL in order of change
+// Returns:
+//
+//	[{
+//	    "original_url": "http://www.ya.ru",
+//	    "changed_at": "2024-05-01T10:00:00Z"
+//	}]
+func (a *API) Revisions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := util.ReadUserIDFromCtx(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := a.store.ReadRevisions(r.Context(), chi.URLParam(r, "alias"), &domain.User{ID: userID})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]*revisionResponse, 0, len(revisions))
+	for _, v := range revisions {
+		resp = append(resp, &revisionResponse{URL: v.URL, ChangedAt: v.ChangedAt})
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", mimeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
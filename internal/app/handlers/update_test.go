@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/user/util"
+)
+
+func TestAPI_UpdateJSON(t *testing.T) {
+	store := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "sale"}, user))
+	require.NoError(t, store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "other"}, &domain.User{ID: 2}))
+
+	api := NewAPI(store, &config.App{BaseShortURL: "http://localhost"}, alias.NewHash(alias.DefaultLength))
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+		})
+	})
+	router.Patch("/api/user/urls/{alias}", api.UpdateJSON)
+	router.Get("/api/user/urls/{alias}/revisions", api.Revisions)
+
+	tests := []struct {
+		name  string
+		alias string
+		body  string
+		want  int
+	}{
+		{name: "updated", alias: "sale", body: `{"url": "http://www.ya.ru/new"}`, want: http.StatusOK},
+		{name: "not_owned", alias: "other", body: `{"url": "http://www.ya.ru/new2"}`, want: http.StatusNotFound},
+		{name: "url_taken", alias: "sale", body: `{"url": "http://www.ya1.ru"}`, want: http.StatusConflict},
+		{name: "empty_url", alias: "sale", body: `{"url": ""}`, want: http.StatusBadRequest},
+		{name: "bad_json", alias: "sale", body: `{`, want: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+test.alias, strings.NewReader(test.body)))
+
+			assert.Equal(t, test.want, w.Code)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/urls/sale/revisions", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var revisions []*revisionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &revisions))
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "http://www.ya.ru", revisions[0].URL)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/urls/other/revisions", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
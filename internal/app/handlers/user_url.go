@@ -2,25 +2,51 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/user/util"
 )
 
 type responseURL struct {
-	URL   string `json:"short_url"`
-	Alias string `json:"original_url"`
+	URL     string `json:"short_url"`
+	Alias   string `json:"original_url"`
+	Deleted bool   `json:"deleted,omitempty"`
 }
 
-// UserURL Handler for list user shortened links
+// responseURLPage page of user links with cursor of next page, empty cursor on last page
+type responseURLPage struct {
+	Items      []*responseURL `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// UserURL Handler for list user shortened links, page is ordered by creation time
+// Query parameters:
+//
+//	limit - count of links in page, 100 by default, max 1000
+//	cursor - opaque cursor of next page
+//	order - asc (default) or desc
+//	contains - substring of original URL
+//	include_deleted - true to return deleted links
+//	envelope - true to return page object with cursor of next page instead of array
+//
+// Link header with rel="next" points to next page, it is absent on last page
 // Returns:
 //
 //	[{
 //	    "short_url": "http://localhost:8080/ZyNJrg",
 //		"original_url": "http://ya.ru"
 //	}]
+//
+// With envelope=true, empty page is returned with status OK:
+//
+//	{
+//	    "items": [{"short_url": "http://localhost:8080/ZyNJrg", "original_url": "http://ya.ru"}],
+//	    "next_cursor": "MjAyNC0wNS0wMV..."
+//	}
 func (a *API) UserURL(w http.ResponseWriter, r *http.Request) {
 	userID, ok := util.ReadUserIDFromCtx(r.Context())
 	if !ok {
@@ -28,34 +54,100 @@ func (a *API) UserURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	batch, err := a.store.ReadUserURL(r.Context(), &domain.User{
+	q, err := parseUserURLQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var envelope bool
+	if v := r.URL.Query().Get("envelope"); v != "" {
+		if envelope, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid envelope: %w", err))
+			return
+		}
+	}
+
+	page, err := a.store.ReadUserURLPage(r.Context(), &domain.User{
 		ID: userID,
-	})
+	}, q)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if len(batch) == 0 {
+	if len(page.Items) == 0 && !envelope {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	resp := make([]*responseURL, 0, len(batch))
-	for _, u := range batch {
+	resp := make([]*responseURL, 0, len(page.Items))
+	for _, u := range page.Items {
 		resp = append(resp, &responseURL{
-			URL:   fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), u.Alias),
-			Alias: u.URL,
+			URL:     fmt.Sprintf("%s/%s", a.cfg.GetBaseShortURL(), u.Alias),
+			Alias:   u.URL,
+			Deleted: u.Deleted,
 		})
 	}
 
-	b, err := json.Marshal(resp)
+	var cursor string
+	if page.Next != nil {
+		cursor = EncodeCursor(page.Next)
+	}
+
+	var b []byte
+	if envelope {
+		b, err = json.Marshal(&responseURLPage{Items: resp, NextCursor: cursor})
+	} else {
+		b, err = json.Marshal(resp)
+	}
+
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	if cursor != "" {
+		next := r.URL.Query()
+		next.Set("cursor", cursor)
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, a.cfg.GetBaseShortURL(), r.URL.Path, next.Encode()))
+	}
+
 	w.Header().Set("content-type", mimeJSON)
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(b)
 }
+
+func parseUserURLQuery(r *http.Request) (*domain.UserURLQuery, error) {
+	query := r.URL.Query()
+
+	var (
+		limit          int
+		includeDeleted bool
+		err            error
+	)
+
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit == 0 {
+			return nil, ErrInvalidLimit
+		}
+	}
+
+	if v := query.Get("include_deleted"); v != "" {
+		if includeDeleted, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid include_deleted: %w", err)
+		}
+	}
+
+	var desc bool
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, errors.New("invalid order, expected asc or desc")
+	}
+
+	return NewUserURLQuery(limit, query.Get("cursor"), desc, query.Get("contains"), includeDeleted)
+}
@@ -2,18 +2,25 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/config"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/handlers/middleware"
 	"github.com/korol8484/shortener/internal/app/storage/memory"
 	"github.com/korol8484/shortener/internal/app/user/storage"
+	"github.com/korol8484/shortener/internal/app/user/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"testing"
+	"time"
 )
 
 func TestAPI_UserURL(t *testing.T) {
@@ -22,7 +29,7 @@ func TestAPI_UserURL(t *testing.T) {
 	defer srv.Close()
 
 	j := middleware.NewJwt(storage.NewMemoryStore(), zap.L(), "123")
-	router.Use(j.HandlerSet(), middleware.LoggRequest(zap.L()), middleware.LoggResponse(zap.L()))
+	router.Use(middleware.AccessLog(zap.L()), j.HandlerSet())
 
 	store := memory.NewMemStore()
 
@@ -38,7 +45,7 @@ func TestAPI_UserURL(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	api := NewAPI(store, &config.App{BaseShortURL: srv.URL})
+	api := NewAPI(store, &config.App{BaseShortURL: srv.URL}, alias.NewHash(alias.DefaultLength))
 	router.Get("/user", api.UserURL)
 
 	req, err := http.NewRequest("GET", srv.URL+"/user", nil)
@@ -51,3 +58,119 @@ func TestAPI_UserURL(t *testing.T) {
 	defer res.Body.Close()
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 }
+
+func TestAPI_UserURL_Page(t *testing.T) {
+	store := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+	created := time.Now()
+
+	for i, u := range []string{"http://a.ru/x", "http://b.ru/x", "http://c.ru/y"} {
+		err := store.Add(context.Background(), &domain.URL{
+			URL:       u,
+			Alias:     fmt.Sprintf("alias%d", i),
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}, user)
+		require.NoError(t, err)
+	}
+
+	_, err := store.BatchDelete(context.Background(), []string{"alias1"}, user.ID)
+	require.NoError(t, err)
+
+	api := NewAPI(store, &config.App{BaseShortURL: "http://localhost"}, alias.NewHash(alias.DefaultLength))
+	next := regexp.MustCompile(`^<(.+)>; rel="next"$`)
+
+	read := func(target string) (*httptest.ResponseRecorder, []*responseURL) {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		api.UserURL(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+
+		var resp []*responseURL
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+
+		return w, resp
+	}
+
+	w, resp := read("/api/user/urls?limit=1&include_deleted=true")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "http://a.ru/x", resp[0].Alias)
+
+	m := next.FindStringSubmatch(w.Header().Get("Link"))
+	require.Len(t, m, 2)
+
+	link, err := url.Parse(m[1])
+	require.NoError(t, err)
+	assert.Equal(t, "1", link.Query().Get("limit"))
+
+	w, resp = read(link.RequestURI())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "http://b.ru/x", resp[0].Alias)
+	assert.True(t, resp[0].Deleted)
+
+	w, resp = read("/api/user/urls?contains=/x&order=desc")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "http://a.ru/x", resp[0].Alias)
+	assert.Empty(t, w.Header().Get("Link"))
+
+	w, resp = read("/api/user/urls?order=desc")
+	require.Len(t, resp, 2)
+	assert.Equal(t, "http://c.ru/y", resp[0].Alias)
+
+	// page object with cursor in body
+	r := httptest.NewRequest(http.MethodGet, "/api/user/urls?limit=1&envelope=true", nil)
+	w = httptest.NewRecorder()
+	api.UserURL(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	page := &responseURLPage{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "http://a.ru/x", page.Items[0].Alias)
+	require.NotEmpty(t, page.NextCursor)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/user/urls?envelope=true&cursor="+page.NextCursor, nil)
+	w = httptest.NewRecorder()
+	api.UserURL(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	page = &responseURLPage{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "http://c.ru/y", page.Items[0].Alias)
+	assert.Empty(t, page.NextCursor)
+
+	// empty page keeps object shape
+	r = httptest.NewRequest(http.MethodGet, "/api/user/urls?envelope=true&contains=none", nil)
+	w = httptest.NewRecorder()
+	api.UserURL(w, r.WithContext(util.SetUserIDToCtx(r.Context(), user.ID)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"items":[]}`, w.Body.String())
+
+	for _, target := range []string{
+		"/api/user/urls?envelope=maybe",
+		"/api/user/urls?limit=0",
+		"/api/user/urls?limit=1001",
+		"/api/user/urls?cursor=broken",
+		"/api/user/urls?order=up",
+		"/api/user/urls?include_deleted=maybe",
+	} {
+		w, _ = read(target)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := &domain.Cursor{CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 123, time.UTC), Alias: "abc"}
+
+	decoded, err := DecodeCursor(EncodeCursor(c))
+	require.NoError(t, err)
+	assert.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, c.Alias, decoded.Alias)
+
+	_, err = DecodeCursor("e30")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
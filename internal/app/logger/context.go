@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey string
+
+const (
+	// keyScope - key to set\read request scoped logger from context
+	keyScope ctxKey = "logger"
+	// keyRequestID - key to set\read request ID from context
+	keyRequestID ctxKey = "request_id"
+)
+
+// scope logger of request, fields added down the chain are seen by middleware which created it
+type scope struct {
+	mu     sync.Mutex
+	logger *zap.Logger
+}
+
+// WithLogger - add request scoped logger in context
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, keyScope, &scope{logger: logger})
+}
+
+// FromContext - read request scoped logger from context, fallback is returned when context has none
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	s, ok := ctx.Value(keyScope).(*scope)
+	if !ok {
+		return fallback
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.logger
+}
+
+// AddFields - add fields to request scoped logger, records logged after it including access log carry them
+func AddFields(ctx context.Context, fields ...zap.Field) {
+	s, ok := ctx.Value(keyScope).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger = s.logger.With(fields...)
+}
+
+// WithRequestID - add request ID in context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyRequestID, id)
+}
+
+// RequestIDFromContext - read request ID from context
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(keyRequestID).(string)
+	if !ok {
+		return "", false
+	}
+
+	return id, true
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	fallback := zap.NewNop()
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+
+	// fields are not added without request scoped logger
+	AddFields(context.Background(), zap.String("k", "v"))
+
+	fac, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithLogger(context.Background(), zap.New(fac))
+
+	AddFields(ctx, zap.Int64("user_id", 1))
+	FromContext(ctx, fallback).Info("msg")
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, int64(1), logs.All()[0].ContextMap()["user_id"])
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	_, ok := RequestIDFromContext(context.Background())
+	assert.False(t, ok)
+
+	id, ok := RequestIDFromContext(WithRequestID(context.Background(), "id"))
+	assert.True(t, ok)
+	assert.Equal(t, "id", id)
+}
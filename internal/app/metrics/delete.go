@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/korol8484/shortener/internal/app/handlers"
+)
+
+// DeleteStater delete worker
+type DeleteStater interface {
+	Stats() handlers.DeleteStats
+}
+
+// deleteCollector reads delete worker stats on every scrape
+type deleteCollector struct {
+	worker  DeleteStater
+	queued  *prometheus.Desc
+	batches *prometheus.Desc
+}
+
+func newDeleteCollector(worker DeleteStater) *deleteCollector {
+	return &deleteCollector{
+		worker: worker,
+		queued: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "delete", "queued_batches"),
+			"Count of delete batches waiting for workers.",
+			nil, nil,
+		),
+		batches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "delete", "batches_total"),
+			"Count of delete batches by outcome, retried - failed attempts which were retried.",
+			[]string{"outcome"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *deleteCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queued
+	ch <- c.batches
+}
+
+// Collect implements prometheus.Collector
+func (c *deleteCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.worker.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(s.Queued))
+	ch <- prometheus.MustNewConstMetric(c.batches, prometheus.CounterValue, float64(s.Deleted), "deleted")
+	ch <- prometheus.MustNewConstMetric(c.batches, prometheus.CounterValue, float64(s.Failed), "failed")
+	ch <- prometheus.MustNewConstMetric(c.batches, prometheus.CounterValue, float64(s.Retried), "retried")
+}
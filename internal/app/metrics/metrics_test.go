@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestMetrics_ObserveHTTP(t *testing.T) {
+	m := New()
+
+	m.ObserveHTTP(http.MethodGet, "/{id}", http.StatusTemporaryRedirect, time.Millisecond)
+	m.ObserveHTTP(http.MethodGet, "/{id}", http.StatusTemporaryRedirect, time.Millisecond)
+	m.ObserveHTTP(http.MethodPost, "/", http.StatusCreated, time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, "/{id}", "307")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues(http.MethodPost, "/", "201")))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `shortener_http_request_duration_seconds_count{method="GET",route="/{id}",status="307"} 2`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+type deleteStats handlers.DeleteStats
+
+func (d deleteStats) Stats() handlers.DeleteStats {
+	return handlers.DeleteStats(d)
+}
+
+func TestMetrics_RegisterDelete(t *testing.T) {
+	m := New()
+	require.NoError(t, m.RegisterDelete(deleteStats{Queued: 3, Deleted: 5, Failed: 1, Retried: 2}))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "shortener_delete_queued_batches 3")
+	assert.Contains(t, body, `shortener_delete_batches_total{outcome="deleted"} 5`)
+	assert.Contains(t, body, `shortener_delete_batches_total{outcome="failed"} 1`)
+	assert.Contains(t, body, `shortener_delete_batches_total{outcome="retried"} 2`)
+}
+
+func TestMetrics_RegisterPgPool(t *testing.T) {
+	// pool opens connections on demand, so stats are read without database
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/shortener?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	m := New()
+	require.NoError(t, m.RegisterPgPool(pool))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, "shortener_db_pool_max_connections 7")
+	assert.Contains(t, body, "shortener_db_pool_acquired_connections 0")
+}
+
+func TestMetrics_RegisterDB(t *testing.T) {
+	db, err := sql.Open("pgx", "postgres://localhost:1/shortener")
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := New()
+	require.NoError(t, m.RegisterDB("pg", db))
+
+	assert.Contains(t, scrape(t, m), `go_sql_open_connections{db_name="pg"} 0`)
+}
+
+func TestUserRepository_NewUser(t *testing.T) {
+	m := New()
+	rep := NewUserRepository(storage.NewMemoryStore(), m)
+
+	for i := 0; i < 2; i++ {
+		_, err := rep.NewUser(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.users))
+}
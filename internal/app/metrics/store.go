@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+// operation results
+const (
+	resultOK       = "ok"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// Store decorator of handlers.Store observing latency of operations,
+// optional capabilities of base store are forwarded
+type Store struct {
+	baseStore handlers.Store
+	metrics   *Metrics
+}
+
+// NewStore Factory
+func NewStore(baseStore handlers.Store, m *Metrics) *Store {
+	return &Store{baseStore: baseStore, metrics: m}
+}
+
+// observe latency of operation started at start
+func (s *Store) observe(operation string, start time.Time, err error) {
+	result := resultOK
+
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		result = resultNotFound
+	case err != nil:
+		result = resultError
+	}
+
+	s.metrics.storeDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+// Add save shorten URL
+func (s *Store) Add(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	start := time.Now()
+	err := s.baseStore.Add(ctx, ent, user)
+	s.observe("add", start, err)
+
+	return err
+}
+
+// Read - read shorten URL by alias
+func (s *Store) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	start := time.Now()
+	u, err := s.baseStore.Read(ctx, alias)
+	s.observe("read", start, err)
+
+	return u, err
+}
+
+// ReadByURL read shorten URL by URL
+func (s *Store) ReadByURL(ctx context.Context, URL string) (*domain.URL, error) {
+	start := time.Now()
+	u, err := s.baseStore.ReadByURL(ctx, URL)
+	s.observe("read_by_url", start, err)
+
+	return u, err
+}
+
+// AddBatch save shorten collection URL
+func (s *Store) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	start := time.Now()
+	res, err := s.baseStore.AddBatch(ctx, batch, user)
+	s.observe("add_batch", start, err)
+
+	return res, err
+}
+
+// ReadUserURL read user shorten URL
+func (s *Store) ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error) {
+	start := time.Now()
+	res, err := s.baseStore.ReadUserURL(ctx, user)
+	s.observe("read_user_url", start, err)
+
+	return res, err
+}
+
+// ReadUserURLPage read page of user shorten URL
+func (s *Store) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	start := time.Now()
+	res, err := s.baseStore.ReadUserURLPage(ctx, user, q)
+	s.observe("read_user_url_page", start, err)
+
+	return res, err
+}
+
+// BatchDelete delete shorten collection URL
+func (s *Store) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	start := time.Now()
+	res, err := s.baseStore.BatchDelete(ctx, aliases, userID)
+	s.observe("batch_delete", start, err)
+
+	return res, err
+}
+
+// Restore un-delete user shorten URL
+func (s *Store) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	start := time.Now()
+	res, err := s.baseStore.Restore(ctx, aliases, userID, since)
+	s.observe("restore", start, err)
+
+	return res, err
+}
+
+// Update change destination of user shorten URL
+func (s *Store) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	start := time.Now()
+	err := s.baseStore.Update(ctx, ent, user)
+	s.observe("update", start, err)
+
+	return err
+}
+
+// ReadUserAlias read user shorten URL by alias
+func (s *Store) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	start := time.Now()
+	res, err := s.baseStore.ReadUserAlias(ctx, alias, user)
+	s.observe("read_user_alias", start, err)
+
+	return res, err
+}
+
+// ReadRevisions read previous destinations of user shorten URL
+func (s *Store) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	start := time.Now()
+	res, err := s.baseStore.ReadRevisions(ctx, alias, user)
+	s.observe("read_revisions", start, err)
+
+	return res, err
+}
+
+// CountURL return count of stored shorten URL
+func (s *Store) CountURL(ctx context.Context) (int64, error) {
+	start := time.Now()
+	res, err := s.baseStore.CountURL(ctx)
+	s.observe("count_url", start, err)
+
+	return res, err
+}
+
+// Purge remove links deleted before given time from base store
+func (s *Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	p, ok := s.baseStore.(handlers.Purgeable)
+	if !ok {
+		return nil, errors.New("base store can't purge links")
+	}
+
+	start := time.Now()
+	res, err := p.Purge(ctx, before)
+	s.observe("purge", start, err)
+
+	return res, err
+}
+
+// DeleteExpired soft delete expired links in base store
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	exp, ok := s.baseStore.(handlers.Expirable)
+	if !ok {
+		return 0, errors.New("base store can't delete expired links")
+	}
+
+	start := time.Now()
+	res, err := exp.DeleteExpired(ctx, now)
+	s.observe("delete_expired", start, err)
+
+	return res, err
+}
+
+// NextID return next alias sequence value from base store
+func (s *Store) NextID(ctx context.Context) (int64, error) {
+	seq, ok := s.baseStore.(alias.Sequence)
+	if !ok {
+		return 0, errors.New("base store has no sequence")
+	}
+
+	start := time.Now()
+	res, err := seq.NextID(ctx)
+	s.observe("next_id", start, err)
+
+	return res, err
+}
+
+// Compact run compaction of base store
+func (s *Store) Compact() error {
+	c, ok := s.baseStore.(handlers.Compactable)
+	if !ok {
+		return errors.New("base store can't compact")
+	}
+
+	start := time.Now()
+	err := c.Compact()
+	s.observe("compact", start, err)
+
+	return err
+}
+
+// Close - base store is closed by its owner
+func (s *Store) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		s := NewStore(memory.NewMemStore(), New())
+
+		return &storagetest.Env{Store: s, NewUser: storagetest.UserFactory(userStorage.NewMemoryStore())}
+	})
+}
+
+func TestStore_Observe(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	s := NewStore(memory.NewMemStore(), m)
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, &domain.User{ID: 1}))
+
+	_, err := s.Read(ctx, "a")
+	require.NoError(t, err)
+
+	_, err = s.Read(ctx, "b")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `shortener_store_operation_duration_seconds_count{operation="add",result="ok"} 1`)
+	assert.Contains(t, body, `shortener_store_operation_duration_seconds_count{operation="read",result="ok"} 1`)
+	assert.Contains(t, body, `shortener_store_operation_duration_seconds_count{operation="read",result="not_found"} 1`)
+}
+
+func TestStore_Capabilities(t *testing.T) {
+	m := New()
+	s := NewStore(memory.NewMemStore(), m)
+
+	id, err := s.NextID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	// memory store can't compact
+	assert.Error(t, s.Compact())
+
+	assert.Contains(t, scrape(t, m), `shortener_store_operation_duration_seconds_count{operation="next_id",result="ok"} 1`)
+}
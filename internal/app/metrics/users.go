@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// UserAdder repository creating users for tokens
+type UserAdder interface {
+	NewUser(ctx context.Context) (*domain.User, error)
+}
+
+// UserRepository decorator counting created users
+type UserRepository struct {
+	base    UserAdder
+	metrics *Metrics
+}
+
+// NewUserRepository Factory
+func NewUserRepository(base UserAdder, m *Metrics) *UserRepository {
+	return &UserRepository{base: base, metrics: m}
+}
+
+// NewUser create user in base repository
+func (u *UserRepository) NewUser(ctx context.Context) (*domain.User, error) {
+	user, err := u.base.NewUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.metrics.users.Inc()
+
+	return user, nil
+}
@@ -0,0 +1,347 @@
+// Package migrations versioned schema migrations for postgresql and sqlite
+//
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// versions are shared by all registered sources and applied in ascending order.
+// Applied versions are stored in schema_migrations table, concurrent runs
+// are serialized by postgresql advisory lock. Sqlite allows one writer, so runs are not locked there.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockID advisory lock key, shared by all replicas
+const lockID int64 = 4523895123
+
+var fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// errors for migrations
+var (
+	// ErrDuplicateVersion - Ошибка что версия миграции уже зарегистрирована
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	// ErrNoUp - Ошибка что у миграции нет up скрипта
+	ErrNoUp = errors.New("migration has no up script")
+	// ErrNoDown - Ошибка что у миграции нет down скрипта
+	ErrNoDown = errors.New("migration has no down script")
+	// ErrUnknownVersion - Ошибка что примененная версия не зарегистрирована
+	ErrUnknownVersion = errors.New("applied migration version not registered")
+)
+
+// Migration one schema version
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status migration state
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// dialect database specific statements of migrator
+type dialect struct {
+	// lock and unlock serialize concurrent runs, empty - runs are not serialized
+	lock   string
+	unlock string
+	// createTable create version table if not exists
+	createTable string
+}
+
+var postgres = dialect{
+	lock:   `SELECT pg_advisory_lock($1)`,
+	unlock: `SELECT pg_advisory_unlock($1)`,
+	createTable: `create table if not exists schema_migrations
+	(
+		version    bigint       not null
+			constraint schema_migrations_pk
+				primary key,
+		name       varchar(255) not null,
+		applied_at timestamptz  default now() not null
+	);`,
+}
+
+var sqlite = dialect{
+	createTable: `create table if not exists schema_migrations
+	(
+		version    integer  not null
+			constraint schema_migrations_pk
+				primary key,
+		name       text     not null,
+		applied_at datetime default current_timestamp not null
+	);`,
+}
+
+// Migrator apply registered migrations
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations map[int64]*Migration
+}
+
+// NewMigrator Factory of postgresql migrator
+func NewMigrator(db *sql.DB) *Migrator {
+	return newMigrator(db, postgres)
+}
+
+// NewSQLiteMigrator Factory of sqlite migrator
+func NewSQLiteMigrator(db *sql.DB) *Migrator {
+	return newMigrator(db, sqlite)
+}
+
+func newMigrator(db *sql.DB, d dialect) *Migrator {
+	return &Migrator{
+		db:         db,
+		dialect:    d,
+		migrations: make(map[int64]*Migration),
+	}
+}
+
+// Register load migration files from dir
+func (m *Migrator) Register(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		match := fileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := m.migrations[version]; ok {
+			return fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+
+		mig, ok := loaded[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			loaded[version] = mig
+		}
+
+		if mig.Name != match[2] {
+			return fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	for v, mig := range loaded {
+		if mig.Up == "" {
+			return fmt.Errorf("%w: %d_%s", ErrNoUp, v, mig.Name)
+		}
+
+		m.migrations[v] = mig
+	}
+
+	return nil
+}
+
+// Up apply all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.sorted() {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			err = m.exec(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name,
+			)
+			if err != nil {
+				return fmt.Errorf("can't apply migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down rollback steps last applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i] > versions[j]
+		})
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := m.migrations[versions[i]]
+			if !ok {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, versions[i])
+			}
+
+			if mig.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, mig.Version, mig.Name)
+			}
+
+			err = m.exec(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("can't rollback migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status return state of registered migrations
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var res []*Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.sorted() {
+			at, ok := applied[mig.Version]
+			res = append(res, &Status{Migration: mig, Applied: ok, AppliedAt: at})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func(conn *sql.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	if m.dialect.lock != "" {
+		// advisory lock belongs to session, so lock, work and unlock on the same connection
+		if _, err = conn.ExecContext(ctx, m.dialect.lock, lockID); err != nil {
+			return err
+		}
+		defer func(conn *sql.Conn) {
+			_, _ = conn.ExecContext(context.Background(), m.dialect.unlock, lockID)
+		}(conn)
+	}
+
+	if _, err = conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			v  int64
+			at time.Time
+		)
+
+		if err = rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+
+		applied[v] = at
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return applied, nil
+}
+
+// exec run migration script and update version table in one transaction
+func (m *Migrator) exec(ctx context.Context, conn *sql.Conn, script string, versionQuery string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}(tx)
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, versionQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) sorted() []*Migration {
+	res := make([]*Migration, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		res = append(res, mig)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	return res
+}
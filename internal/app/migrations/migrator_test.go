@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFS = fstest.MapFS{
+	"m/00001_create_a.up.sql":   {Data: []byte("create table a (id int);")},
+	"m/00001_create_a.down.sql": {Data: []byte("drop table a;")},
+	"m/00002_create_b.up.sql":   {Data: []byte("create table b (id int);")},
+	"m/README.md":               {Data: []byte("skip")},
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrator_Register(t *testing.T) {
+	m := NewMigrator(nil)
+
+	require.NoError(t, m.Register(testFS, "m"))
+	require.Len(t, m.sorted(), 2)
+
+	assert.Equal(t, int64(1), m.sorted()[0].Version)
+	assert.Equal(t, "create_a", m.sorted()[0].Name)
+	assert.Equal(t, "drop table a;", m.sorted()[0].Down)
+	assert.Equal(t, "", m.sorted()[1].Down)
+
+	require.ErrorIs(t, m.Register(testFS, "m"), ErrDuplicateVersion)
+
+	err := NewMigrator(nil).Register(fstest.MapFS{
+		"m/00001_create_a.down.sql": {Data: []byte("drop table a;")},
+	}, "m")
+	require.ErrorIs(t, err, ErrNoUp)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	require.NoError(t, m.Register(testFS, "m"))
+
+	expectLock(mock)
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("create table b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "create_b").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	require.NoError(t, m.Up(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	require.NoError(t, m.Register(testFS, "m"))
+
+	expectLock(mock)
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("drop table a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	require.NoError(t, m.Down(context.Background(), 1))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	expectLock(mock)
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(2, time.Now()))
+	expectUnlock(mock)
+
+	require.ErrorIs(t, m.Down(context.Background(), 1), ErrNoDown)
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	require.NoError(t, m.Register(testFS, "m"))
+
+	at := time.Now()
+
+	expectLock(mock)
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, at))
+	expectUnlock(mock)
+
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+
+	assert.True(t, statuses[0].Applied)
+	assert.Equal(t, at, statuses[0].AppliedAt)
+	assert.False(t, statuses[1].Applied)
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewSQLiteMigrator(db)
+	require.NoError(t, m.Register(testFS, "m"))
+
+	// runs are not locked
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("create table a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(1, "create_a").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("create table b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(2, "create_b").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, m.Up(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
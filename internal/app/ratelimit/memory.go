@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery count of Allow calls between removals of full buckets
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	quota  Quota
+}
+
+// Memory token buckets of process, limits are not shared between instances
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+// NewMemory Factory
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow take token from bucket of key
+func (m *Memory) Allow(_ context.Context, key string, q Quota) (*Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	m.calls++
+	if m.calls%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(q.Limit), at: now}
+		m.buckets[key] = b
+	}
+
+	var res *Result
+
+	b.tokens, res = take(q, b.tokens, now.Sub(b.at))
+	b.at, b.quota = now, q
+
+	return res, nil
+}
+
+// sweep remove buckets refilled to full, they are the same as absent ones
+func (m *Memory) sweep(now time.Time) {
+	for k, b := range m.buckets {
+		if now.Sub(b.at) >= b.quota.Period {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+// Close - nothing to close
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package ratelimit token bucket limits of requests, buckets are kept in process or in redis
+// to share limits between service instances
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuota - Ошибка что квота задана неверно
+var ErrInvalidQuota = errors.New("invalid rate limit quota")
+
+// Quota bucket of Limit tokens refilled evenly during Period, zero Limit - requests are not limited
+type Quota struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseQuota parse quota in format "limit/period" like "60/1m", empty string and "0" - not limited
+func ParseQuota(s string) (Quota, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Quota{}, nil
+	}
+
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Quota{}, fmt.Errorf("%w: %s, expected limit/period", ErrInvalidQuota, s)
+	}
+
+	l, err := strconv.Atoi(limit)
+	if err != nil || l < 0 {
+		return Quota{}, fmt.Errorf("%w: %s, limit must be positive number", ErrInvalidQuota, s)
+	}
+
+	p, err := time.ParseDuration(period)
+	if err != nil || p <= 0 {
+		return Quota{}, fmt.Errorf("%w: %s, period must be positive duration", ErrInvalidQuota, s)
+	}
+
+	return Quota{Limit: l, Period: p}, nil
+}
+
+// Enabled check requests are limited
+func (q Quota) Enabled() bool {
+	return q.Limit > 0
+}
+
+// String format quota like "60/1m0s"
+func (q Quota) String() string {
+	return fmt.Sprintf("%d/%s", q.Limit, q.Period)
+}
+
+// rate tokens added per nanosecond
+func (q Quota) rate() float64 {
+	return float64(q.Limit) / float64(q.Period)
+}
+
+// Result outcome of request to take token
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining tokens left in bucket
+	Remaining int
+	// Reset time until bucket is full
+	Reset time.Duration
+	// RetryAfter time until next token, zero when request is allowed
+	RetryAfter time.Duration
+}
+
+// Limiter storage of token buckets
+type Limiter interface {
+	// Allow take token from bucket of key
+	Allow(ctx context.Context, key string, q Quota) (*Result, error)
+	Close() error
+}
+
+// take refill bucket with tokens left after elapsed time and take token from it
+func take(q Quota, tokens float64, elapsed time.Duration) (float64, *Result) {
+	if elapsed > 0 {
+		tokens = math.Min(float64(q.Limit), tokens+float64(elapsed)*q.rate())
+	}
+
+	res := &Result{Limit: q.Limit}
+
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / q.rate()))
+	}
+
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration(math.Ceil((float64(q.Limit) - tokens) / q.rate()))
+
+	return tokens, res
+}
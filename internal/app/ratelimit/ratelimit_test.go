@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuota(t *testing.T) {
+	q, err := ParseQuota("60/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Quota{Limit: 60, Period: time.Minute}, q)
+	assert.True(t, q.Enabled())
+
+	for _, s := range []string{"", "0", " "} {
+		q, err = ParseQuota(s)
+		require.NoError(t, err)
+		assert.False(t, q.Enabled())
+	}
+
+	for _, s := range []string{"60", "a/1m", "-1/1m", "60/m", "60/0s"} {
+		_, err = ParseQuota(s)
+		assert.ErrorIs(t, err, ErrInvalidQuota, s)
+	}
+}
+
+func TestMemory_Allow(t *testing.T) {
+	now := time.Now()
+	q := Quota{Limit: 2, Period: 2 * time.Second}
+
+	m := NewMemory()
+	m.now = func() time.Time {
+		return now
+	}
+
+	for i := 1; i >= 0; i-- {
+		res, err := m.Allow(context.Background(), "a", q)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := m.Allow(context.Background(), "a", q)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	// buckets of keys are separate
+	res, err = m.Allow(context.Background(), "b", q)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// one token is refilled per second
+	now = now.Add(time.Second)
+
+	res, err = m.Allow(context.Background(), "a", q)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// full buckets are removed
+	now = now.Add(time.Hour)
+	m.sweep(now)
+	assert.Empty(t, m.buckets)
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix prefix of bucket keys in redis
+const keyPrefix = "shortener:ratelimit:"
+
+// takeScript refill bucket and take token atomically, bucket expires when it is full anyway.
+// Returns allowed flag and tokens left as string to keep fraction
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(b[1]) or limit
+local at = tonumber(b[2]) or now
+
+tokens = math.min(limit, tokens + math.max(0, now - at) * limit / period)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], period)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisConfig shared buckets configuration
+type RedisConfig interface {
+	GetRateLimitRedisURL() string
+}
+
+// Redis token buckets in redis or any server speaking its protocol, shared by service instances.
+// Time of instances is used to refill buckets, so their clocks must be synchronized
+type Redis struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+// NewRedis Factory, connection is checked by ping
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	opts, err := redis.ParseURL(cfg.GetRateLimitRedisURL())
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &Redis{client: client, now: time.Now}, nil
+}
+
+// Allow take token from bucket of key
+func (r *Redis) Allow(ctx context.Context, key string, q Quota) (*Result, error) {
+	period := q.Period.Milliseconds()
+	if period < 1 {
+		period = 1
+	}
+
+	reply, err := takeScript.Run(ctx, r.client, []string{keyPrefix + key}, q.Limit, period, r.now().UnixMilli()).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reply) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+
+	left, ok := reply[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// script already took token, result is computed for tokens before it
+	if reply[0] == int64(1) {
+		tokens++
+	}
+
+	_, res := take(q, tokens, 0)
+
+	return res, nil
+}
+
+// Close - close connection
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/config"
+)
+
+func TestRedis_Allow(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	now := time.Now()
+	q := Quota{Limit: 2, Period: 2 * time.Second}
+
+	newLimiter := func() *Redis {
+		r, err := NewRedis(&config.App{RateLimitRedisURL: "redis://" + srv.Addr()})
+		require.NoError(t, err)
+
+		r.now = func() time.Time {
+			return now
+		}
+
+		t.Cleanup(func() {
+			_ = r.Close()
+		})
+
+		return r
+	}
+
+	// instances share buckets
+	first, second := newLimiter(), newLimiter()
+
+	res, err := first.Allow(ctx, "a", q)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, err = second.Allow(ctx, "a", q)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, err = first.Allow(ctx, "a", q)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2*time.Second, srv.TTL(keyPrefix+"a"))
+
+	now = now.Add(1500 * time.Millisecond)
+
+	res, err = second.Allow(ctx, "a", q)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestNewRedis(t *testing.T) {
+	_, err := NewRedis(&config.App{RateLimitRedisURL: "http://localhost"})
+	assert.Error(t, err)
+}
@@ -11,4 +11,6 @@ var (
 	ErrNotFound = errors.New("can't find requested alias")
 	// ErrIssetURL - Ошибка что запрошенные данные уже есть
 	ErrIssetURL = errors.New("requested url isset")
+	// ErrAliasTaken - Ошибка что alias уже занят другим URL
+	ErrAliasTaken = errors.New("requested alias taken")
 )
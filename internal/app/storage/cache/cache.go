@@ -0,0 +1,279 @@
+// Package cache caching decorator of links store, redirect reads are served from in process LRU
+// and optional redis tier shared by instances
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/logger"
+)
+
+// Config in process cache configuration
+type Config interface {
+	GetCacheSize() int
+	GetCacheTTL() time.Duration
+}
+
+// Store caching decorator of handlers.Store. Links read by alias are cached, entries are
+// invalidated when links are deleted, restored, purged or change destination.
+// With remote tier invalidations are published to process caches of all instances,
+// without it other instances serve changed link until cache ttl.
+// Unknown aliases are not cached, so added links are visible at once
+type Store struct {
+	baseStore handlers.Store
+	local     *lru
+	// remote optional cache tier, nil - disabled
+	remote Remote
+	// group collapses concurrent misses of same alias to one base store read
+	group  singleflight.Group
+	logger *zap.Logger
+}
+
+// NewStore Factory, remote may be nil. Store subscribes to invalidations of remote tier
+func NewStore(baseStore handlers.Store, cfg Config, remote Remote, logger *zap.Logger) (*Store, error) {
+	if cfg.GetCacheSize() < 1 {
+		return nil, errors.New("cache size must be positive")
+	}
+
+	if cfg.GetCacheTTL() <= 0 {
+		return nil, errors.New("cache ttl must be positive")
+	}
+
+	s := &Store{
+		baseStore: baseStore,
+		local:     newLRU(cfg.GetCacheSize(), cfg.GetCacheTTL()),
+		remote:    remote,
+		logger:    logger,
+	}
+
+	if remote != nil {
+		if err := remote.Subscribe(context.Background(), s.evict); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Read - read shorten URL from cache, base store is read on miss
+func (s *Store) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	if u, ok := s.local.get(alias); ok {
+		return u, nil
+	}
+
+	// loaded link is shared by callers, so load is not canceled with first caller
+	v, err, _ := s.group.Do(alias, func() (interface{}, error) {
+		return s.load(context.WithoutCancel(ctx), alias)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u := *v.(*domain.URL)
+
+	return &u, nil
+}
+
+// Add save shorten URL
+func (s *Store) Add(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	return s.baseStore.Add(ctx, ent, user)
+}
+
+// AddBatch save shorten collection URL
+func (s *Store) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	return s.baseStore.AddBatch(ctx, batch, user)
+}
+
+// ReadByURL read shorten URL by URL, not cached
+func (s *Store) ReadByURL(ctx context.Context, URL string) (*domain.URL, error) {
+	return s.baseStore.ReadByURL(ctx, URL)
+}
+
+// ReadUserURL read user shorten URL, not cached
+func (s *Store) ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error) {
+	return s.baseStore.ReadUserURL(ctx, user)
+}
+
+// ReadUserURLPage read page of user shorten URL, not cached
+func (s *Store) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	return s.baseStore.ReadUserURLPage(ctx, user, q)
+}
+
+// BatchDelete delete shorten collection URL and invalidate deleted links
+func (s *Store) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	deleted, err := s.baseStore.BatchDelete(ctx, aliases, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, deleted)
+
+	return deleted, nil
+}
+
+// Restore un-delete user shorten URL and invalidate restored links
+func (s *Store) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	restored, err := s.baseStore.Restore(ctx, aliases, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, restored)
+
+	return restored, nil
+}
+
+// Update change destination of user shorten URL and invalidate link
+func (s *Store) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	if err := s.baseStore.Update(ctx, ent, user); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx, []string{ent.Alias})
+
+	return nil
+}
+
+// ReadUserAlias read user shorten URL by alias
+func (s *Store) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	return s.baseStore.ReadUserAlias(ctx, alias, user)
+}
+
+// ReadRevisions read previous destinations of user shorten URL
+func (s *Store) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	return s.baseStore.ReadRevisions(ctx, alias, user)
+}
+
+// CountURL return count of stored shorten URL
+func (s *Store) CountURL(ctx context.Context) (int64, error) {
+	return s.baseStore.CountURL(ctx)
+}
+
+// Purge remove links deleted before given time from base store and invalidate them
+func (s *Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	p, ok := s.baseStore.(handlers.Purgeable)
+	if !ok {
+		return nil, errors.New("base store can't purge links")
+	}
+
+	purged, err := p.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx, purged)
+
+	return purged, nil
+}
+
+// DeleteExpired soft delete expired links in base store. Cached links are not invalidated
+// as expiration time is cached with link
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	exp, ok := s.baseStore.(handlers.Expirable)
+	if !ok {
+		return 0, errors.New("base store can't delete expired links")
+	}
+
+	return exp.DeleteExpired(ctx, now)
+}
+
+// NextID return next alias sequence value from base store
+func (s *Store) NextID(ctx context.Context) (int64, error) {
+	seq, ok := s.baseStore.(alias.Sequence)
+	if !ok {
+		return 0, errors.New("base store has no sequence")
+	}
+
+	return seq.NextID(ctx)
+}
+
+// Compact run compaction of base store
+func (s *Store) Compact() error {
+	c, ok := s.baseStore.(handlers.Compactable)
+	if !ok {
+		return errors.New("base store can't compact")
+	}
+
+	return c.Compact()
+}
+
+// Close - close remote tier, base store is closed by its owner
+func (s *Store) Close() error {
+	if s.remote == nil {
+		return nil
+	}
+
+	return s.remote.Close()
+}
+
+// load read link from remote tier or base store and cache it. Link is not cached
+// when it was invalidated while loading
+func (s *Store) load(ctx context.Context, alias string) (*domain.URL, error) {
+	epoch := s.local.epoch()
+
+	if s.remote != nil {
+		u, err := s.remote.Get(ctx, alias)
+		if err != nil {
+			logger.FromContext(ctx, s.logger).Warn("can't read link from remote cache", zap.String("alias", alias), zap.Error(err))
+		}
+
+		if u != nil {
+			s.local.setIf(u, epoch)
+			return u, nil
+		}
+	}
+
+	u, err := s.baseStore.Read(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.remote != nil {
+		if err = s.remote.Set(ctx, u); err != nil {
+			logger.FromContext(ctx, s.logger).Warn("can't save link to remote cache", zap.String("alias", alias), zap.Error(err))
+		}
+	}
+
+	// invalidation raced with load, remote entry may be stale
+	if !s.local.setIf(u, epoch) && s.remote != nil {
+		s.deleteRemote(ctx, []string{alias})
+	}
+
+	return u, nil
+}
+
+func (s *Store) invalidate(ctx context.Context, aliases []string) {
+	if len(aliases) == 0 {
+		return
+	}
+
+	s.evict(aliases)
+
+	if s.remote != nil {
+		s.deleteRemote(ctx, aliases)
+	}
+}
+
+// evict drop links from process cache, called on change and on invalidation by other instance
+func (s *Store) evict(aliases []string) {
+	s.local.remove(aliases)
+
+	// next read of alias starts new load instead of joining load started before change
+	for _, alias := range aliases {
+		s.group.Forget(alias)
+	}
+}
+
+func (s *Store) deleteRemote(ctx context.Context, aliases []string) {
+	if err := s.remote.Delete(ctx, aliases); err != nil {
+		logger.FromContext(ctx, s.logger).Error("can't invalidate remote cache", zap.Strings("aliases", aliases), zap.Error(err))
+	}
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+// countStore counts reads of base store, reads wait for gate when it is set
+type countStore struct {
+	*memory.MemStore
+	reads atomic.Int64
+	gate  chan struct{}
+}
+
+func (c *countStore) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	c.reads.Add(1)
+
+	if c.gate != nil {
+		<-c.gate
+	}
+
+	return c.MemStore.Read(ctx, alias)
+}
+
+func newStore(t *testing.T, remote Remote) (*Store, *countStore) {
+	base := &countStore{MemStore: memory.NewMemStore()}
+
+	s, err := NewStore(base, &config.App{CacheSize: 2, CacheTTL: time.Minute}, remote, zap.L())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return s, base
+}
+
+func TestNewStore(t *testing.T) {
+	_, err := NewStore(memory.NewMemStore(), &config.App{CacheTTL: time.Minute}, nil, zap.L())
+	assert.Error(t, err)
+
+	_, err = NewStore(memory.NewMemStore(), &config.App{CacheSize: 1}, nil, zap.L())
+	assert.Error(t, err)
+}
+
+func TestStore_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		s, _ := newStore(t, nil)
+
+		return &storagetest.Env{Store: s, NewUser: storagetest.UserFactory(userStorage.NewMemoryStore())}
+	})
+}
+
+func TestStore_Read(t *testing.T) {
+	ctx := context.Background()
+	s, base := newStore(t, nil)
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, user))
+
+	_, err := s.Read(ctx, "unknown")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	for i := 0; i < 3; i++ {
+		u, rErr := s.Read(ctx, "a")
+		require.NoError(t, rErr)
+		assert.Equal(t, "http://ya.ru", u.URL)
+
+		// caller can't change cached link
+		u.URL = "changed"
+	}
+
+	assert.Equal(t, int64(2), base.reads.Load())
+
+	// misses are not cached
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru/unknown", Alias: "unknown"}, user))
+
+	_, err = s.Read(ctx, "unknown")
+	require.NoError(t, err)
+}
+
+func TestStore_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	s, base := newStore(t, nil)
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, user))
+
+	_, err := s.Read(ctx, "a")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Update(ctx, &domain.URL{URL: "http://ya.ru/new", Alias: "a"}, user))
+
+	u, err := s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://ya.ru/new", u.URL)
+
+	_, err = s.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+
+	u, err = s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, u.Deleted)
+
+	_, err = s.Restore(ctx, []string{"a"}, user.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	u, err = s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, u.Deleted)
+
+	_, err = s.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+
+	_, err = s.Read(ctx, "a")
+	require.NoError(t, err)
+
+	purged, err := s.Purge(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, purged)
+
+	_, err = s.Read(ctx, "a")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.Equal(t, int64(6), base.reads.Load())
+}
+
+func TestStore_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	s, base := newStore(t, nil)
+	base.gate = make(chan struct{})
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, &domain.User{ID: 1}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			u, err := s.Read(ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, "http://ya.ru", u.URL)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		return base.reads.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// let waiting readers join the load
+	time.Sleep(10 * time.Millisecond)
+	close(base.gate)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), base.reads.Load())
+}
+
+func TestStore_InvalidateWhileLoading(t *testing.T) {
+	ctx := context.Background()
+	s, base := newStore(t, nil)
+	base.gate = make(chan struct{})
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, user))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_, _ = s.Read(ctx, "a")
+	}()
+
+	require.Eventually(t, func() bool {
+		return base.reads.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Update(ctx, &domain.URL{URL: "http://ya.ru/new", Alias: "a"}, user))
+	close(base.gate)
+	<-done
+
+	u, err := s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://ya.ru/new", u.URL)
+}
+
+func TestStore_Remote(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+
+	remote, err := NewRedis(&config.App{CacheRedisURL: "redis://" + srv.Addr(), CacheRedisTTL: time.Minute})
+	require.NoError(t, err)
+
+	s, base := newStore(t, remote)
+	user := &domain.User{ID: 1}
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a", ExpiresAt: expiresAt}, user))
+
+	_, err = s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, srv.Exists(keyPrefix+"a"))
+	assert.Equal(t, time.Minute, srv.TTL(keyPrefix+"a"))
+
+	// instance with empty process cache reads link from shared tier
+	other, err := NewStore(base, &config.App{CacheSize: 2, CacheTTL: time.Minute}, remote, zap.L())
+	require.NoError(t, err)
+
+	u, err := other.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://ya.ru", u.URL)
+	assert.True(t, expiresAt.Equal(u.ExpiresAt))
+	assert.Equal(t, int64(1), base.reads.Load())
+
+	_, err = s.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+	assert.False(t, srv.Exists(keyPrefix+"a"))
+
+	// broken shared tier falls back to base store
+	srv.Close()
+
+	u, err = s.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, u.Deleted)
+}
+
+func TestStore_RemoteInvalidate(t *testing.T) {
+	ctx := context.Background()
+	srv := miniredis.RunT(t)
+	cfg := &config.App{CacheSize: 2, CacheTTL: time.Minute, CacheRedisURL: "redis://" + srv.Addr(), CacheRedisTTL: time.Minute}
+	base := memory.NewMemStore()
+	user := &domain.User{ID: 1}
+
+	// instances share base store and redis, every instance has own process cache
+	newInstance := func() *Store {
+		remote, err := NewRedis(cfg)
+		require.NoError(t, err)
+
+		s, err := NewStore(base, cfg, remote, zap.L())
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			_ = s.Close()
+		})
+
+		return s
+	}
+
+	first, second := newInstance(), newInstance()
+
+	require.NoError(t, first.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, user))
+
+	_, err := first.Read(ctx, "a")
+	require.NoError(t, err)
+
+	require.NoError(t, second.Update(ctx, &domain.URL{URL: "http://ya.ru/new", Alias: "a"}, user))
+
+	// change made by second instance evicts link from process cache of first one
+	assert.Eventually(t, func() bool {
+		u, err := first.Read(ctx, "a")
+		return err == nil && u.URL == "http://ya.ru/new"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewRedis(t *testing.T) {
+	_, err := NewRedis(&config.App{CacheRedisURL: "http://localhost"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+type entry struct {
+	alias     string
+	url       domain.URL
+	expiresAt time.Time
+}
+
+// lru in process links cache, least recently used link is evicted when cache is full
+// and link is dropped on read after ttl
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[string]*list.Element
+	order *list.List
+	// gen changed by every remove, link read from store before remove is stale
+	gen uint64
+	now func() time.Time
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element, size),
+		order: list.New(),
+		now:   time.Now,
+	}
+}
+
+// get return copy of cached link
+func (l *lru) get(alias string) (*domain.URL, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[alias]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !l.now().Before(e.expiresAt) {
+		l.removeElement(el)
+		return nil, false
+	}
+
+	l.order.MoveToFront(el)
+	u := e.url
+
+	return &u, true
+}
+
+// epoch return generation to pass to setIf
+func (l *lru) epoch() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.gen
+}
+
+// setIf save copy of link when nothing was removed since epoch was taken
+func (l *lru) setIf(ent *domain.URL, epoch uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.gen != epoch {
+		return false
+	}
+
+	expiresAt := l.now().Add(l.ttl)
+
+	if el, ok := l.items[ent.Alias]; ok {
+		e := el.Value.(*entry)
+		e.url, e.expiresAt = *ent, expiresAt
+		l.order.MoveToFront(el)
+
+		return true
+	}
+
+	l.items[ent.Alias] = l.order.PushFront(&entry{alias: ent.Alias, url: *ent, expiresAt: expiresAt})
+
+	for l.order.Len() > l.size {
+		l.removeElement(l.order.Back())
+	}
+
+	return true
+}
+
+func (l *lru) remove(aliases []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gen++
+
+	for _, alias := range aliases {
+		if el, ok := l.items[alias]; ok {
+			l.removeElement(el)
+		}
+	}
+}
+
+func (l *lru) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*entry).alias)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+
+	l := newLRU(2, time.Minute)
+	l.now = func() time.Time {
+		return now
+	}
+
+	for _, alias := range []string{"a", "b"} {
+		require.True(t, l.setIf(&domain.URL{Alias: alias, URL: "http://" + alias}, l.epoch()))
+	}
+
+	// a becomes recently used, so b is evicted
+	_, ok := l.get("a")
+	require.True(t, ok)
+
+	require.True(t, l.setIf(&domain.URL{Alias: "c", URL: "http://c"}, l.epoch()))
+	assert.Equal(t, 2, l.len())
+
+	_, ok = l.get("b")
+	assert.False(t, ok)
+
+	u, ok := l.get("c")
+	require.True(t, ok)
+	assert.Equal(t, "http://c", u.URL)
+
+	epoch := l.epoch()
+	l.remove([]string{"c"})
+
+	_, ok = l.get("c")
+	assert.False(t, ok)
+	assert.False(t, l.setIf(&domain.URL{Alias: "c"}, epoch))
+
+	now = now.Add(time.Minute)
+
+	_, ok = l.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, l.len())
+}
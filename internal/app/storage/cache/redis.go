@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// keyPrefix prefix of link keys in redis
+const keyPrefix = "shortener:url:"
+
+// invalidateChannel channel of aliases invalidated by instances
+const invalidateChannel = "shortener:invalidate"
+
+// Remote cache tier shared by service instances
+type Remote interface {
+	// Get return cached link, nil without error when link is not cached
+	Get(ctx context.Context, alias string) (*domain.URL, error)
+	Set(ctx context.Context, ent *domain.URL) error
+	// Delete drop cached links and notify subscribed instances
+	Delete(ctx context.Context, aliases []string) error
+	// Subscribe call handler with aliases invalidated by any instance until Close
+	Subscribe(ctx context.Context, handler func(aliases []string)) error
+	Close() error
+}
+
+// RedisConfig redis tier configuration
+type RedisConfig interface {
+	GetCacheRedisURL() string
+	GetCacheRedisTTL() time.Duration
+}
+
+// Redis cache tier in redis or any server speaking its protocol
+type Redis struct {
+	mu     sync.Mutex
+	client *redis.Client
+	ttl    time.Duration
+	subs   []*redis.PubSub
+}
+
+type redisURL struct {
+	URL       string    `json:"url"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewRedis Factory, connection is checked by ping
+func NewRedis(cfg RedisConfig) (*Redis, error) {
+	opts, err := redis.ParseURL(cfg.GetCacheRedisURL())
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	if err = client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return &Redis{client: client, ttl: cfg.GetCacheRedisTTL()}, nil
+}
+
+// Get return cached link, nil without error when link is not cached
+func (r *Redis) Get(ctx context.Context, alias string) (*domain.URL, error) {
+	b, err := r.client.Get(ctx, keyPrefix+alias).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var v redisURL
+	if err = json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	return &domain.URL{
+		URL:       v.URL,
+		Alias:     alias,
+		Deleted:   v.Deleted,
+		ExpiresAt: v.ExpiresAt,
+		CreatedAt: v.CreatedAt,
+	}, nil
+}
+
+// Set save link for ttl
+func (r *Redis) Set(ctx context.Context, ent *domain.URL) error {
+	b, err := json.Marshal(&redisURL{
+		URL:       ent.URL,
+		Deleted:   ent.Deleted,
+		ExpiresAt: ent.ExpiresAt,
+		CreatedAt: ent.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, keyPrefix+ent.Alias, b, r.ttl).Err()
+}
+
+// Delete drop cached links and publish aliases to invalidate process caches of instances
+func (r *Redis) Delete(ctx context.Context, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		keys = append(keys, keyPrefix+alias)
+	}
+
+	msg, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		pipe.Publish(ctx, invalidateChannel, msg)
+
+		return nil
+	})
+
+	return err
+}
+
+// Subscribe call handler with aliases invalidated by any instance, handler runs in own goroutine.
+// Returns when subscription is confirmed, so invalidations published after it are delivered
+func (r *Redis) Subscribe(ctx context.Context, handler func(aliases []string)) error {
+	sub := r.client.Subscribe(ctx, invalidateChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	r.subs = append(r.subs, sub)
+	r.mu.Unlock()
+
+	go func() {
+		for msg := range sub.Channel() {
+			var aliases []string
+			if err := json.Unmarshal([]byte(msg.Payload), &aliases); err != nil {
+				continue
+			}
+
+			handler(aliases)
+		}
+	}()
+
+	return nil
+}
+
+// Close - close subscriptions and connection
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sub := range r.subs {
+		_ = sub.Close()
+	}
+
+	r.subs = nil
+
+	return r.client.Close()
+}
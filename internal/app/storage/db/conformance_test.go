@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	appdb "github.com/korol8484/shortener/internal/app/db"
+	"github.com/korol8484/shortener/internal/app/migrations"
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+// pgPool pool of real postgresql from TEST_DATABASE_DSN with migrated schema,
+// test is skipped when DSN is not set
+func pgPool(tb testing.TB) (*pgxpool.Pool, *sql.DB) {
+	pgDsn := os.Getenv("TEST_DATABASE_DSN")
+	if pgDsn == "" {
+		tb.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	pool, err := appdb.NewPgPool(context.Background(), &config.App{DBDsn: pgDsn})
+	require.NoError(tb, err)
+
+	tb.Cleanup(pool.Close)
+
+	conn := appdb.SQLFromPool(pool)
+
+	m := migrations.NewMigrator(conn)
+	require.NoError(tb, userStorage.RegisterMigrations(m))
+	require.NoError(tb, RegisterMigrations(m))
+	require.NoError(tb, m.Up(context.Background()))
+
+	return pool, conn
+}
+
+func TestStorage_Conformance(t *testing.T) {
+	pool, conn := pgPool(t)
+
+	users, err := userStorage.NewStorage(conn)
+	require.NoError(t, err)
+
+	pgStore, err := NewStorage(pool)
+	require.NoError(t, err)
+
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		return &storagetest.Env{Store: pgStore, NewUser: storagetest.UserFactory(users)}
+	})
+}
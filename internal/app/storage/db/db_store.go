@@ -2,335 +2,550 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
 	"strings"
-	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
 	"github.com/korol8484/shortener/internal/app/storage"
 )
 
-// Storage - Db storage
-type Storage struct {
-	mu sync.RWMutex
-	db *sql.DB
+const (
+	// aliasUniqueIndex unique index on alias, created by migration 00005
+	aliasUniqueIndex = "shortener_uidx_alias"
+	// urlUniqueIndex unique index on url, created by migration 00002
+	urlUniqueIndex = "shortener_uidx_url"
+)
+
+// hot path queries, pool prepares them once per connection
+const (
+	queryRead      = `SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t WHERE alias = $1`
+	queryReadByURL = `SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t WHERE url = $1`
+	queryReadUser  = `SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t INNER JOIN user_url uu on t.id = uu.url_id WHERE t.alias = $1 AND uu.user_id = $2`
+	queryAdd       = `INSERT INTO shortener (url, alias, expires_at) VALUES ($1,$2,$3) ON CONFLICT (url) DO NOTHING RETURNING id`
+	queryAddOwner  = `INSERT INTO user_url (user_id, url_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`
+	// queryAddBatch insert links from arrays and owner of inserted links in one statement, returns inserted URL
+	queryAddBatch = `WITH ins AS (
+		INSERT INTO shortener (url, alias, expires_at)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::timestamptz[])
+		ON CONFLICT (url) DO NOTHING RETURNING id, url
+	), owner AS (
+		INSERT INTO user_url (user_id, url_id) SELECT $4, id FROM ins ON CONFLICT DO NOTHING
+	)
+	SELECT url FROM ins`
+	queryReadByURLs  = `SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t WHERE url = ANY($1::text[])`
+	queryBatchDelete = `UPDATE shortener s SET deleted = true, deleted_at = coalesce(s.deleted_at, now()) FROM user_url uu
+		WHERE s.id = uu.url_id AND uu.user_id = $1 AND s.alias = ANY($2::text[]) RETURNING s.alias`
+	queryRestore = `UPDATE shortener s SET deleted = false, deleted_at = NULL FROM user_url uu
+		WHERE s.id = uu.url_id AND uu.user_id = $1 AND s.deleted = true AND s.deleted_at >= $2
+		AND (s.expires_at IS NULL OR s.expires_at > now()) AND s.alias = ANY($3::text[]) RETURNING s.alias`
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// Pool postgresql connections pool, implemented by *pgxpool.Pool
+type Pool interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Close()
 }
 
-// NewStorage - DB storage Factory
-func NewStorage(db *sql.DB) (*Storage, error) {
-	st := &Storage{db: db}
+// Storage - Db storage, concurrent calls run on separate pool connections
+type Storage struct {
+	pool Pool
+}
 
-	err := st.migrate(context.Background())
-	if err != nil {
-		return nil, err
-	}
+// NewStorage - DB storage Factory, schema must be created by RegisterMigrations
+func NewStorage(pool Pool) (*Storage, error) {
+	return &Storage{pool: pool}, nil
+}
 
-	return st, nil
+// RegisterMigrations register shortener schema migrations
+func RegisterMigrations(m *migrations.Migrator) error {
+	return m.Register(migrationFS, "migrations")
 }
 
 // Add save shorten URL
 func (s *Storage) Add(ctx context.Context, ent *domain.URL, user *domain.User) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer func(tx *sql.Tx) {
+	defer func(tx pgx.Tx) {
 		if err != nil {
-			_ = tx.Rollback()
+			_ = tx.Rollback(ctx)
 		}
 	}(tx)
 
 	var id int64
 
-	err = tx.QueryRowContext(
-		ctx, `INSERT INTO shortener (url, alias) VALUES ($1,$2) ON CONFLICT (url) DO NOTHING RETURNING id`, ent.URL, ent.Alias,
-	).Scan(&id)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return err
-	}
-
-	var isset bool
-	if id < 1 {
-		isset = true
-
-		err = s.db.QueryRowContext(ctx, "SELECT t.id FROM shortener t WHERE url = $1", ent.URL).Scan(&id)
-		if err != nil {
-			return err
+	err = tx.QueryRow(ctx, queryAdd, ent.URL, ent.Alias, nullTime(ent.ExpiresAt)).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		if aliasTaken(err) {
+			return storage.ErrAliasTaken
 		}
-	}
 
-	_, err = tx.ExecContext(
-		ctx, `INSERT INTO user_url (user_id, url_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`, user.ID, id,
-	)
-	if err != nil {
 		return err
 	}
 
-	if err = tx.Commit(); err != nil {
+	// URL shortened before stays owned by user created it
+	if id < 1 {
+		err = storage.ErrIssetURL
 		return err
 	}
 
-	if isset {
-		return storage.ErrIssetURL
+	if _, err = tx.Exec(ctx, queryAddOwner, user.ID, id); err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
-// AddBatch save shorten collection URL
-func (s *Storage) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+// AddBatch save shorten collection URL, links are passed as arrays in one statement.
+// Returns outcome per link in order of batch, URL shortened before, also earlier in the same batch,
+// is not saved and returns existing link
+func (s *Storage) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	if len(batch) == 0 {
+		return nil, nil
 	}
 
-	defer func(tx *sql.Tx) {
-		if err != nil {
-			_ = tx.Rollback()
+	// first link of URL in batch, the others refer to it
+	first := make(map[string]*domain.URL, len(batch))
+	urls := make([]string, 0, len(batch))
+	aliases := make([]string, 0, len(batch))
+	expires := make([]pgtype.Timestamptz, 0, len(batch))
+
+	for _, v := range batch {
+		if _, ok := first[v.URL]; ok {
+			continue
 		}
-	}(tx)
 
-	var (
-		placeholders []string
-		vals         []interface{}
-	)
+		first[v.URL] = v
+		urls = append(urls, v.URL)
+		aliases = append(aliases, v.Alias)
+		expires = append(expires, nullTime(v.ExpiresAt))
+	}
 
-	for i, v := range batch {
-		placeholders = append(placeholders, fmt.Sprintf("($%d,$%d)",
-			i*2+1,
-			i*2+2,
-		))
+	var inserted []string
 
-		vals = append(vals, v.URL, v.Alias)
+	// unique violation is reported by Query or by reading rows
+	rows, err := s.pool.Query(ctx, queryAddBatch, urls, aliases, expires, user.ID)
+	if err == nil {
+		inserted, err = pgx.CollectRows(rows, pgx.RowTo[string])
 	}
 
-	insert := fmt.Sprintf("INSERT INTO shortener (url, alias) VALUES %s ON CONFLICT (url) DO UPDATE SET url=EXCLUDED.url RETURNING id", strings.Join(placeholders, ","))
-	rows, err := tx.QueryContext(ctx, insert, vals...)
 	if err != nil {
-		return err
+		if aliasTaken(err) {
+			return nil, storage.ErrAliasTaken
+		}
+
+		return nil, err
 	}
 
-	if rows.Err() != nil {
-		return rows.Err()
+	created := make(map[string]struct{}, len(inserted))
+	for _, v := range inserted {
+		created[v] = struct{}{}
 	}
 
-	defer rows.Close()
+	existing, err := s.readByURLs(ctx, urls, created)
+	if err != nil {
+		return nil, err
+	}
 
-	ids := make([]int64, 0, len(batch))
-	for rows.Next() {
-		var n int64
+	results := make([]*domain.BatchResult, 0, len(batch))
+	for _, v := range batch {
+		_, ok := created[v.URL]
 
-		if err = rows.Scan(&n); err != nil {
-			return err
+		switch {
+		case ok && first[v.URL] == v:
+			results = append(results, &domain.BatchResult{URL: v, Status: domain.BatchItemCreated})
+		case ok:
+			results = append(results, &domain.BatchResult{URL: first[v.URL], Status: domain.BatchItemExisting})
+		default:
+			results = append(results, &domain.BatchResult{URL: existing[v.URL], Status: domain.BatchItemExisting})
 		}
-
-		ids = append(ids, n)
 	}
 
-	var (
-		userPlaceholders []string
-		userVals         []interface{}
-	)
+	return results, nil
+}
 
-	for i, v := range ids {
-		userPlaceholders = append(userPlaceholders, fmt.Sprintf("($%d,$%d)",
-			i*2+1,
-			i*2+2,
-		))
+// readByURLs read links of URL except skipped, every URL must be shortened
+func (s *Storage) readByURLs(ctx context.Context, urls []string, skip map[string]struct{}) (map[string]*domain.URL, error) {
+	lookup := make([]string, 0, len(urls)-len(skip))
+	for _, v := range urls {
+		if _, ok := skip[v]; !ok {
+			lookup = append(lookup, v)
+		}
+	}
 
-		userVals = append(userVals, user.ID, v)
+	if len(lookup) == 0 {
+		return nil, nil
 	}
 
-	insertUser := fmt.Sprintf("INSERT INTO user_url (user_id, url_id) VALUES %s ON CONFLICT DO NOTHING", strings.Join(userPlaceholders, ","))
-	_, err = tx.ExecContext(ctx, insertUser, userVals...)
+	rows, err := s.pool.Query(ctx, queryReadByURLs, lookup)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return err
+	defer rows.Close()
+
+	found := make(map[string]*domain.URL, len(lookup))
+	for rows.Next() {
+		var expiresAt pgtype.Timestamptz
+
+		u := &domain.URL{}
+		if err = rows.Scan(&u.URL, &u.Alias, &u.Deleted, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		u.ExpiresAt = expiresAt.Time
+		found[u.URL] = u
 	}
 
-	return nil
-}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
 
-// BatchDelete delete shorten collection URL
-func (s *Storage) BatchDelete(ctx context.Context, aliases []string, userID int64) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// link purged after conflict of insert
+	if len(found) != len(lookup) {
+		return nil, storage.ErrNotFound
+	}
 
-	var (
-		placeholders []string
-		vals         []interface{}
-	)
+	return found, nil
+}
+
+// BatchDelete delete shorten collection URL, returns deleted aliases owned by user
+func (s *Storage) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
 
-	vals = append(vals, userID)
-	for i, v := range aliases {
-		placeholders = append(placeholders, fmt.Sprintf("$%d",
-			i+2,
-		))
+	return s.queryAliases(ctx, queryBatchDelete, userID, aliases)
+}
 
-		vals = append(vals, v)
+// Restore un-delete user shorten URL deleted since given time, expired links are not restored.
+// Returns restored aliases
+func (s *Storage) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
 	}
 
-	up := fmt.Sprintf(
-		"UPDATE shortener s SET deleted = true FROM user_url uu WHERE s.id = uu.url_id AND uu.user_id = $1 AND s.alias IN (%s);",
-		strings.Join(placeholders, ","),
-	)
+	return s.queryAliases(ctx, queryRestore, userID, since, aliases)
+}
 
-	_, err := s.db.ExecContext(ctx, up, vals...)
+// Purge remove links deleted before given time, owners and revisions are removed by cascade.
+// Returns removed aliases
+func (s *Storage) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	return s.queryAliases(ctx, "DELETE FROM shortener WHERE deleted = true AND deleted_at < $1 RETURNING alias", before)
+}
+
+// queryAliases run query returning alias column
+func (s *Storage) queryAliases(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return pgx.CollectRows(rows, pgx.RowTo[string])
 }
 
 // ReadUserURL read user shorten URL
 func (s *Storage) ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	rows, err := s.db.QueryContext(ctx, "SELECT s.url, s.alias FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id WHERE uu.user_id = $1;", user.ID)
+	rows, err := s.pool.Query(
+		ctx,
+		"SELECT s.url, s.alias, s.deleted, s.expires_at FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id WHERE uu.user_id = $1",
+		user.ID,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-
 	defer rows.Close()
 
 	var batch domain.BatchURL
 	for rows.Next() {
-		var u domain.URL
-		if err = rows.Scan(&u.URL, &u.Alias); err != nil {
+		var expiresAt pgtype.Timestamptz
+
+		u := &domain.URL{}
+		if err = rows.Scan(&u.URL, &u.Alias, &u.Deleted, &expiresAt); err != nil {
 			return nil, err
 		}
 
-		batch = append(batch, &domain.URL{
-			URL:   u.URL,
-			Alias: u.Alias,
-		})
+		u.ExpiresAt = expiresAt.Time
+
+		batch = append(batch, u)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
 	}
 
 	return batch, nil
 }
 
-// Read - read shorten URL
-func (s *Storage) Read(ctx context.Context, alias string) (*domain.URL, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ReadUserURLPage read page of user shorten URL ordered by creation time, one extra row is
+// selected to know whether next page exists
+func (s *Storage) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	where := []string{"uu.user_id = $1"}
+	vals := []any{user.ID}
 
-	row := s.db.QueryRowContext(ctx, "SELECT t.url, t.alias, t.deleted FROM shortener t WHERE alias = $1", alias)
-	if row.Err() != nil {
-		return nil, row.Err()
+	if !q.IncludeDeleted {
+		where = append(where, "s.deleted = false")
 	}
 
-	ent := &domain.URL{}
-	err := row.Scan(&ent.URL, &ent.Alias, &ent.Deleted)
+	if q.Contains != "" {
+		vals = append(vals, q.Contains)
+		where = append(where, fmt.Sprintf("strpos(s.url, $%d) > 0", len(vals)))
+	}
+
+	order, cmp := "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		vals = append(vals, q.After.CreatedAt, q.After.Alias)
+		where = append(where, fmt.Sprintf("(s.created_at, s.alias) %s ($%d, $%d)", cmp, len(vals)-1, len(vals)))
+	}
+
+	vals = append(vals, q.Limit+1)
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(
+		"SELECT s.url, s.alias, s.deleted, s.expires_at, s.created_at FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id WHERE %s ORDER BY s.created_at %s, s.alias %s LIMIT $%d",
+		strings.Join(where, " AND "), order, order, len(vals),
+	), vals...)
 	if err != nil {
 		return nil, err
 	}
 
-	return ent, nil
+	defer rows.Close()
+
+	page := &domain.UserURLPage{Items: make(domain.BatchURL, 0, q.Limit)}
+	for rows.Next() {
+		var expiresAt pgtype.Timestamptz
+
+		u := &domain.URL{}
+		if err = rows.Scan(&u.URL, &u.Alias, &u.Deleted, &expiresAt, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		u.ExpiresAt = expiresAt.Time
+
+		if len(page.Items) == q.Limit {
+			page.Next = domain.CursorOf(page.Items[len(page.Items)-1])
+			break
+		}
+
+		page.Items = append(page.Items, u)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return page, nil
+}
+
+// Read - read shorten URL
+func (s *Storage) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	return s.readOne(ctx, queryRead, alias)
 }
 
 // ReadByURL read shorten URL by URL
 func (s *Storage) ReadByURL(ctx context.Context, URL string) (*domain.URL, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.readOne(ctx, queryReadByURL, URL)
+}
 
-	row := s.db.QueryRowContext(ctx, "SELECT t.url, t.alias FROM shortener t WHERE url = $1", URL)
-	if row.Err() != nil {
-		return nil, row.Err()
-	}
+// ReadUserAlias read user shorten URL by alias, deleted links are read too.
+// Returns storage.ErrNotFound when link is not owned by user
+func (s *Storage) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	return s.readOne(ctx, queryReadUser, alias, user.ID)
+}
+
+// readOne read link by query, storage.ErrNotFound when link is absent
+func (s *Storage) readOne(ctx context.Context, query string, args ...any) (*domain.URL, error) {
+	var expiresAt pgtype.Timestamptz
 
 	ent := &domain.URL{}
-	err := row.Scan(&ent.URL, &ent.Alias)
+
+	err := s.pool.QueryRow(ctx, query, args...).Scan(&ent.URL, &ent.Alias, &ent.Deleted, &expiresAt)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+
 		return nil, err
 	}
 
-	return ent, nil
-}
+	ent.ExpiresAt = expiresAt.Time
 
-// Close - close db
-func (s *Storage) Close() error {
-	return s.db.Close()
+	return ent, nil
 }
 
-func (s *Storage) migrate(ctx context.Context) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+// Update change destination of user shorten URL and save previous destination to revision table.
+// Returns storage.ErrNotFound when link is not owned by user or deleted
+// and storage.ErrIssetURL when new destination is shortened by another link
+func (s *Storage) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer func(tx *sql.Tx) {
+	defer func(tx pgx.Tx) {
 		if err != nil {
-			_ = tx.Rollback()
+			_ = tx.Rollback(ctx)
 		}
 	}(tx)
 
-	_, err = tx.ExecContext(ctx, `
-	create table if not exists shortener
-	(
-		id    bigserial
-			constraint shortener_pk
-				primary key,
-		url   varchar(1000) not null,
-		alias varchar(10)   not null
-	);`)
+	var (
+		id  int64
+		old string
+	)
+
+	err = tx.QueryRow(
+		ctx,
+		`SELECT s.id, s.url FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id
+		WHERE s.alias = $1 AND uu.user_id = $2 AND s.deleted = false FOR UPDATE OF s`,
+		ent.Alias, user.ID,
+	).Scan(&id, &old)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, `create index if not exists shortener_alias_index on shortener (alias);`)
-	if err != nil {
-		return err
+	if old != ent.URL {
+		if _, err = tx.Exec(ctx, `UPDATE shortener SET url = $1 WHERE id = $2`, ent.URL, id); err != nil {
+			if urlTaken(err) {
+				return storage.ErrIssetURL
+			}
+
+			return err
+		}
+
+		_, err = tx.Exec(
+			ctx, `INSERT INTO shortener_revision (url_id, user_id, url) VALUES ($1,$2,$3)`, id, user.ID, old,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = tx.ExecContext(ctx, `create unique index if not exists shortener_uidx_url ON shortener (url);`)
+	return tx.Commit(ctx)
+}
+
+// ReadRevisions read previous destinations of user shorten URL in order of change
+func (s *Storage) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	var id int64
+
+	err := s.pool.QueryRow(
+		ctx,
+		`SELECT s.id FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id
+		WHERE s.alias = $1 AND uu.user_id = $2 AND s.deleted = false`,
+		alias, user.ID,
+	).Scan(&id)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+
+		return nil, err
 	}
 
-	_, err = tx.ExecContext(ctx, `alter table shortener add IF NOT EXISTS deleted bool default false not null;`)
+	rows, err := s.pool.Query(
+		ctx, `SELECT r.url, r.user_id, r.changed_at FROM shortener_revision r WHERE r.url_id = $1 ORDER BY r.id`, id,
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.ExecContext(ctx, `create table if not exists user_url
-	(
-		user_id bigserial
-			constraint user_url_user_id_fk
-				references "user"
-				on delete cascade,
-		url_id  bigserial
-			constraint user_url_shortener_id_fk
-				references shortener
-				on delete cascade
-	);`)
-	if err != nil {
-		return err
+	defer rows.Close()
+
+	revisions := make([]*domain.Revision, 0)
+	for rows.Next() {
+		rev := &domain.Revision{Alias: alias}
+		if err = rows.Scan(&rev.URL, &rev.UserID, &rev.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
 	}
 
-	_, err = tx.ExecContext(ctx, `create unique index if not exists user_url_url_id_user_id_uindex on user_url (url_id, user_id);`)
+	return revisions, nil
+}
+
+// DeleteExpired soft delete links expired at time now, returns count of deleted links
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.pool.Exec(
+		ctx, "UPDATE shortener SET deleted = true, deleted_at = $1 WHERE deleted = false AND expires_at <= $1", now,
+	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return err
+	return tag.RowsAffected(), nil
+}
+
+// NextID return next alias sequence value
+func (s *Storage) NextID(ctx context.Context) (int64, error) {
+	var id int64
+
+	if err := s.pool.QueryRow(ctx, `SELECT nextval('shortener_alias_seq')`).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CountURL return count of stored shorten URL
+func (s *Storage) CountURL(ctx context.Context) (int64, error) {
+	var n int64
+
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM shortener`).Scan(&n); err != nil {
+		return 0, err
 	}
 
+	return n, nil
+}
+
+// Close - close pool
+func (s *Storage) Close() error {
+	s.pool.Close()
+
 	return nil
 }
+
+// nullTime convert zero time to NULL
+func nullTime(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: !t.IsZero()}
+}
+
+// aliasTaken check error is unique violation of alias index
+func aliasTaken(err error) bool {
+	var pgErr *pgconn.PgError
+
+	// 23505 - unique_violation
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == aliasUniqueIndex
+}
+
+// urlTaken check error is unique violation of url index
+func urlTaken(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == urlUniqueIndex
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+// lockedStorage serialises reads on one mutex as storage did before pool was used,
+// kept to compare with concurrent reads
+type lockedStorage struct {
+	mu sync.Mutex
+	*Storage
+}
+
+func (l *lockedStorage) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.Storage.Read(ctx, alias)
+}
+
+type reader interface {
+	Read(ctx context.Context, alias string) (*domain.URL, error)
+}
+
+func benchURLs(n int) domain.BatchURL {
+	batch := make(domain.BatchURL, 0, n)
+	for i := 0; i < n; i++ {
+		id := strings.ReplaceAll(uuid.NewString(), "-", "")
+		batch = append(batch, &domain.URL{URL: "http://" + id + ".ru", Alias: id[:10]})
+	}
+
+	return batch
+}
+
+// BenchmarkStorage_Read parallel redirect reads, pool against global mutex
+func BenchmarkStorage_Read(b *testing.B) {
+	pool, conn := pgPool(b)
+
+	users, err := userStorage.NewStorage(conn)
+	require.NoError(b, err)
+
+	user, err := users.NewUser(context.Background())
+	require.NoError(b, err)
+
+	store, err := NewStorage(pool)
+	require.NoError(b, err)
+
+	batch := benchURLs(100)
+	_, err = store.AddBatch(context.Background(), batch, user)
+	require.NoError(b, err)
+
+	for _, bench := range []struct {
+		name  string
+		store reader
+	}{
+		{"pool", store},
+		{"mutex", &lockedStorage{Storage: store}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetParallelism(4)
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if _, rErr := bench.store.Read(context.Background(), batch[i%len(batch)].Alias); rErr != nil {
+						b.Error(rErr)
+					}
+
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStorage_AddBatch batch insert of links passed as arrays
+func BenchmarkStorage_AddBatch(b *testing.B) {
+	pool, conn := pgPool(b)
+
+	users, err := userStorage.NewStorage(conn)
+	require.NoError(b, err)
+
+	user, err := users.NewUser(context.Background())
+	require.NoError(b, err)
+
+	store, err := NewStorage(pool)
+	require.NoError(b, err)
+
+	for _, size := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				batch := benchURLs(size)
+				b.StartTimer()
+
+				if _, aErr := store.AddBatch(context.Background(), batch, user); aErr != nil {
+					b.Fatal(aErr)
+				}
+			}
+		})
+	}
+}
@@ -2,20 +2,24 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
 	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/pashagolub/pgxmock/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
+	"regexp"
 	"testing"
+	"time"
 )
 
 var (
-	db    *sql.DB
-	mock  sqlmock.Sqlmock
+	mock  pgxmock.PgxPoolIface
 	store *Storage
 )
 
@@ -35,21 +39,12 @@ func TestMain(m *testing.M) {
 
 func run(m *testing.M) (int, error) {
 	var err error
-	db, mock, err = sqlmock.New()
+	mock, err = pgxmock.NewPool()
 	if err != nil {
 		return -1, err
 	}
 
-	mock.ExpectBegin()
-	mock.ExpectExec("create table if not exists shortener .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("create index .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("create unique .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("alter table shortener .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("create table if not exists user_url.*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("create unique index.*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
-
-	store, err = NewStorage(db)
+	store, err = NewStorage(mock)
 	if err != nil {
 		return -1, err
 	}
@@ -60,11 +55,11 @@ func run(m *testing.M) (int, error) {
 func TestStorage_Add(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO shortener").
-		WithArgs("1", "1").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WithArgs("1", "1", pgtype.Timestamptz{}).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)))
 	mock.ExpectExec("INSERT INTO user_url").
-		WithArgs(1, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		WithArgs(int64(1), int64(1)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mock.ExpectCommit()
 
 	err := store.Add(context.Background(), &domain.URL{URL: "1", Alias: "1"}, &domain.User{ID: 1})
@@ -72,28 +67,24 @@ func TestStorage_Add(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO shortener").
-		WithArgs("2", "2").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(0))
+		WithArgs("2", "2", pgtype.Timestamptz{}).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
 
-	mock.ExpectQuery("SELECT t.id FROM shortener t").
-		WithArgs("2").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
-
-	mock.ExpectExec("INSERT INTO user_url").
-		WithArgs(2, 2).
-		WillReturnResult(sqlmock.NewResult(2, 1))
-	mock.ExpectCommit()
+	mock.ExpectRollback()
 
 	err = store.Add(context.Background(), &domain.URL{URL: "2", Alias: "2"}, &domain.User{ID: 2})
 	require.ErrorIs(t, err, storage.ErrIssetURL)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestStorage_Read(t *testing.T) {
-	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted FROM shortener t").
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t").
 		WithArgs("alias").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"url", "alias", "deleted"}).
-				AddRow("http://ya.ru", "alias", false),
+			pgxmock.NewRows([]string{"url", "alias", "deleted", "expires_at"}).
+				AddRow("http://ya.ru", "alias", false, expiresAt),
 		)
 
 	url, err := store.Read(context.Background(), "alias")
@@ -102,20 +93,21 @@ func TestStorage_Read(t *testing.T) {
 	assert.Equal(t, "http://ya.ru", url.URL)
 	assert.Equal(t, "alias", url.Alias)
 	assert.False(t, url.Deleted)
+	assert.Equal(t, expiresAt, url.ExpiresAt)
 
-	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted FROM shortener t").
-		WithArgs("alias").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t").
+		WithArgs("alias").WillReturnError(pgx.ErrNoRows)
 
 	_, err = store.Read(context.Background(), "alias")
-	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.ErrorIs(t, err, storage.ErrNotFound)
 }
 
 func TestStorage_ReadByURL(t *testing.T) {
-	mock.ExpectQuery("SELECT t.url, t.alias FROM shortener t").
+	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t").
 		WithArgs("http://ya.ru").
 		WillReturnRows(
-			sqlmock.NewRows([]string{"url", "alias"}).
-				AddRow("http://ya.ru", "alias"),
+			pgxmock.NewRows([]string{"url", "alias", "deleted", "expires_at"}).
+				AddRow("http://ya.ru", "alias", false, nil),
 		)
 
 	url, err := store.ReadByURL(context.Background(), "http://ya.ru")
@@ -124,64 +116,264 @@ func TestStorage_ReadByURL(t *testing.T) {
 	assert.Equal(t, "http://ya.ru", url.URL)
 	assert.Equal(t, "alias", url.Alias)
 	assert.False(t, url.Deleted)
+	assert.True(t, url.ExpiresAt.IsZero())
+
+	mock.ExpectQuery("SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t").
+		WithArgs("http://unknown.ru").WillReturnError(pgx.ErrNoRows)
+
+	_, err = store.ReadByURL(context.Background(), "http://unknown.ru")
+	require.ErrorIs(t, err, storage.ErrNotFound)
 }
 
 func TestStorage_ReadUserURL(t *testing.T) {
-	mock.ExpectQuery("SELECT s.url, s.alias FROM shortener s").
-		WithArgs(1).
+	mock.ExpectQuery("SELECT s.url, s.alias, s.deleted, s.expires_at FROM shortener s").
+		WithArgs(int64(1)).
 		WillReturnRows(
-			sqlmock.NewRows([]string{"url", "alias"}).
-				AddRow("http://ya.ru", "alias"),
+			pgxmock.NewRows([]string{"url", "alias", "deleted", "expires_at"}).
+				AddRow("http://ya.ru", "alias", true, nil),
 		)
 
 	url, err := store.ReadUserURL(context.Background(), &domain.User{ID: 1})
 	require.NoError(t, err)
 
-	assert.Len(t, url, 1)
+	require.Len(t, url, 1)
+	assert.True(t, url[0].Deleted)
 }
 
-func TestStorage_BatchDelete(t *testing.T) {
-	mock.ExpectExec("UPDATE shortener s SET deleted = true").
-		WithArgs(1, "alias").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+func TestStorage_ReadUserURLPage(t *testing.T) {
+	created := time.Now()
 
-	err := store.BatchDelete(context.Background(), []string{"alias"}, 1)
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE uu.user_id = $1 AND s.deleted = false AND strpos(s.url, $2) > 0 AND (s.created_at, s.alias) < ($3, $4) ORDER BY s.created_at DESC, s.alias DESC LIMIT $5")).
+		WithArgs(int64(1), "ya", created, "c", 2).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"url", "alias", "deleted", "expires_at", "created_at"}).
+				AddRow("http://ya.ru/b", "b", false, nil, created.Add(-time.Second)).
+				AddRow("http://ya.ru/a", "a", false, nil, created.Add(-2*time.Second)),
+		)
+
+	page, err := store.ReadUserURLPage(context.Background(), &domain.User{ID: 1}, &domain.UserURLQuery{
+		Limit:    1,
+		After:    &domain.Cursor{CreatedAt: created, Alias: "c"},
+		Desc:     true,
+		Contains: "ya",
+	})
 	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "b", page.Items[0].Alias)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, "b", page.Next.Alias)
 }
 
-func TestStorage_AddBatch(t *testing.T) {
+func TestStorage_Update(t *testing.T) {
 	mock.ExpectBegin()
-	mock.ExpectQuery("INSERT INTO shortener").
-		WithArgs("1", "1").
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectExec("INSERT INTO user_url").
-		WithArgs(1, 1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT s.id, s.url FROM shortener s").
+		WithArgs("alias", int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "url"}).AddRow(int64(3), "http://ya.ru"))
+	mock.ExpectExec("UPDATE shortener SET url").
+		WithArgs("http://ya.ru/new", int64(3)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("INSERT INTO shortener_revision").
+		WithArgs(int64(3), int64(1), "http://ya.ru").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
 	mock.ExpectCommit()
 
-	err := store.AddBatch(context.Background(), domain.BatchURL{
+	err := store.Update(context.Background(), &domain.URL{URL: "http://ya.ru/new", Alias: "alias"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT s.id, s.url FROM shortener s").
+		WithArgs("alias", int64(2)).
+		WillReturnError(pgx.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = store.Update(context.Background(), &domain.URL{URL: "http://ya.ru/new", Alias: "alias"}, &domain.User{ID: 2})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT s.id, s.url FROM shortener s").
+		WithArgs("alias", int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "url"}).AddRow(int64(3), "http://ya.ru"))
+	mock.ExpectExec("UPDATE shortener SET url").
+		WithArgs("http://ya.ru/taken", int64(3)).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: urlUniqueIndex})
+	mock.ExpectRollback()
+
+	err = store.Update(context.Background(), &domain.URL{URL: "http://ya.ru/taken", Alias: "alias"}, &domain.User{ID: 1})
+	require.ErrorIs(t, err, storage.ErrIssetURL)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_ReadRevisions(t *testing.T) {
+	changed := time.Now()
+
+	mock.ExpectQuery("SELECT s.id FROM shortener s").
+		WithArgs("alias", int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(3)))
+	mock.ExpectQuery("SELECT r.url, r.user_id, r.changed_at FROM shortener_revision r").
+		WithArgs(int64(3)).
+		WillReturnRows(pgxmock.NewRows([]string{"url", "user_id", "changed_at"}).AddRow("http://ya.ru", int64(1), changed))
+
+	revisions, err := store.ReadRevisions(context.Background(), "alias", &domain.User{ID: 1})
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "http://ya.ru", revisions[0].URL)
+	assert.Equal(t, "alias", revisions[0].Alias)
+}
+
+func TestStorage_BatchDelete(t *testing.T) {
+	mock.ExpectQuery("UPDATE shortener s SET deleted = true").
+		WithArgs(int64(1), []string{"alias"}).
+		WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow("alias"))
+
+	deleted, err := store.BatchDelete(context.Background(), []string{"alias"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alias"}, deleted)
+
+	deleted, err = store.BatchDelete(context.Background(), nil, 1)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_Restore(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+
+	mock.ExpectQuery("UPDATE shortener s SET deleted = false, deleted_at = NULL").
+		WithArgs(int64(1), since, []string{"alias", "other"}).
+		WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow("alias"))
+
+	restored, err := store.Restore(context.Background(), []string{"alias", "other"}, 1, since)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alias"}, restored)
+
+	restored, err = store.Restore(context.Background(), nil, 1, since)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+}
+
+func TestStorage_Purge(t *testing.T) {
+	before := time.Now()
+
+	mock.ExpectQuery("DELETE FROM shortener WHERE deleted = true AND deleted_at").
+		WithArgs(before).
+		WillReturnRows(pgxmock.NewRows([]string{"alias"}).AddRow("a").AddRow("b"))
+
+	purged, err := store.Purge(context.Background(), before)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, purged)
+}
+
+func TestStorage_AddBatch(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM unnest($1::text[], $2::text[], $3::timestamptz[])")).
+		WithArgs(
+			[]string{"1", "2", "4"},
+			[]string{"1", "2", "4"},
+			[]pgtype.Timestamptz{{}, {Time: expiresAt, Valid: true}, {}},
+			int64(1),
+		).
+		WillReturnRows(pgxmock.NewRows([]string{"url"}).AddRow("1").AddRow("2"))
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE url = ANY($1::text[])")).
+		WithArgs([]string{"4"}).
+		WillReturnRows(pgxmock.NewRows([]string{"url", "alias", "deleted", "expires_at"}).
+			AddRow("4", "5", false, pgtype.Timestamptz{}))
+
+	results, err := store.AddBatch(context.Background(), domain.BatchURL{
 		&domain.URL{URL: "1", Alias: "1"},
+		&domain.URL{URL: "2", Alias: "2", ExpiresAt: expiresAt},
+		&domain.URL{URL: "1", Alias: "3"},
+		&domain.URL{URL: "4", Alias: "4"},
 	}, &domain.User{ID: 1})
-	assert.NoError(t, err)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	for i, want := range []struct {
+		alias  string
+		status domain.BatchItemStatus
+	}{
+		{"1", domain.BatchItemCreated},
+		{"2", domain.BatchItemCreated},
+		{"1", domain.BatchItemExisting},
+		{"5", domain.BatchItemExisting},
+	} {
+		assert.Equal(t, want.alias, results[i].URL.Alias)
+		assert.Equal(t, want.status, results[i].Status)
+	}
+
+	mock.ExpectQuery("INSERT INTO user_url").
+		WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: aliasUniqueIndex})
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{&domain.URL{URL: "3", Alias: "1"}}, &domain.User{ID: 1})
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	results, err = store.AddBatch(context.Background(), nil, &domain.User{ID: 1})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestStorage_Close(t *testing.T) {
-	cDB, CMock, err := sqlmock.New()
+func TestStorage_AliasTaken(t *testing.T) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO shortener").
+		WithArgs("3", "1", pgtype.Timestamptz{}).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: aliasUniqueIndex})
+	mock.ExpectRollback()
+
+	err := store.Add(context.Background(), &domain.URL{URL: "3", Alias: "1"}, &domain.User{ID: 1})
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorage_NextID(t *testing.T) {
+	mock.ExpectQuery("SELECT nextval").
+		WillReturnRows(pgxmock.NewRows([]string{"nextval"}).AddRow(int64(7)))
+
+	id, err := store.NextID(context.Background())
 	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+}
 
-	CMock.ExpectBegin()
-	CMock.ExpectExec("create table if not exists shortener .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectExec("create index .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectExec("create unique .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectExec("alter table shortener .*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectExec("create table if not exists user_url.*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectExec("create unique index.*").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	CMock.ExpectCommit()
+func TestStorage_DeleteExpired(t *testing.T) {
+	now := time.Now()
 
-	cStore, err := NewStorage(cDB)
+	mock.ExpectExec("UPDATE shortener SET deleted = true, deleted_at = \\$1 WHERE deleted = false AND expires_at").
+		WithArgs(now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	n, err := store.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestStorage_CountURL(t *testing.T) {
+	mock.ExpectQuery("SELECT count").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(5)))
+
+	n, err := store.CountURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+}
+
+func TestStorage_Close(t *testing.T) {
+	CMock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	cStore, err := NewStorage(CMock)
 	require.NoError(t, err)
 
 	CMock.ExpectClose()
 	err = cStore.Close()
 	require.NoError(t, err)
+	require.NoError(t, CMock.ExpectationsWereMet())
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	m := migrations.NewMigrator(nil)
+
+	require.NoError(t, RegisterMigrations(m))
+	require.ErrorIs(t, RegisterMigrations(m), migrations.ErrDuplicateVersion)
 }
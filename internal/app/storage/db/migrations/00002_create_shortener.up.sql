@@ -0,0 +1,12 @@
+create table if not exists shortener
+(
+    id    bigserial
+        constraint shortener_pk
+            primary key,
+    url   varchar(1000) not null,
+    alias varchar(10)   not null
+);
+
+create index if not exists shortener_alias_index on shortener (alias);
+
+create unique index if not exists shortener_uidx_url ON shortener (url);
@@ -0,0 +1 @@
+alter table shortener drop column if exists deleted;
@@ -0,0 +1 @@
+alter table shortener add if not exists deleted bool default false not null;
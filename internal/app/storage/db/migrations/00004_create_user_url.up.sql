@@ -0,0 +1,13 @@
+create table if not exists user_url
+(
+    user_id bigserial
+        constraint user_url_user_id_fk
+            references "user"
+            on delete cascade,
+    url_id  bigserial
+        constraint user_url_shortener_id_fk
+            references shortener
+            on delete cascade
+);
+
+create unique index if not exists user_url_url_id_user_id_uindex on user_url (url_id, user_id);
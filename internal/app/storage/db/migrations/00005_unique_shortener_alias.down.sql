@@ -0,0 +1,7 @@
+drop sequence if exists shortener_alias_seq;
+
+drop index if exists shortener_uidx_alias;
+
+alter table shortener alter column alias type varchar(10);
+
+create index if not exists shortener_alias_index on shortener (alias);
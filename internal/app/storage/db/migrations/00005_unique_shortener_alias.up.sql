@@ -0,0 +1,8 @@
+-- fails when table already has duplicate aliases, they must be resolved manually
+drop index if exists shortener_alias_index;
+
+alter table shortener alter column alias type varchar(32);
+
+create unique index if not exists shortener_uidx_alias on shortener (alias);
+
+create sequence if not exists shortener_alias_seq;
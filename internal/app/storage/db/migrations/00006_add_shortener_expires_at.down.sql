@@ -0,0 +1,3 @@
+drop index if exists shortener_idx_expires_at;
+
+alter table shortener drop column if exists expires_at;
@@ -0,0 +1,3 @@
+alter table shortener add column if not exists expires_at timestamptz;
+
+create index if not exists shortener_idx_expires_at on shortener (expires_at) where deleted = false;
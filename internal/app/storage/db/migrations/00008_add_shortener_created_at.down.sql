@@ -0,0 +1,5 @@
+drop index if exists user_url_idx_user_id;
+
+drop index if exists shortener_idx_created_at_alias;
+
+alter table shortener drop column if exists created_at;
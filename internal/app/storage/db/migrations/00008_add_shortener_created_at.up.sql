@@ -0,0 +1,5 @@
+alter table shortener add column if not exists created_at timestamptz not null default now();
+
+create index if not exists shortener_idx_created_at_alias on shortener (created_at, alias);
+
+create index if not exists user_url_idx_user_id on user_url (user_id);
@@ -0,0 +1,15 @@
+create table if not exists shortener_revision
+(
+    id         bigserial
+        constraint shortener_revision_pk
+            primary key,
+    url_id     bigint                    not null
+        constraint shortener_revision_shortener_id_fk
+            references shortener
+            on delete cascade,
+    user_id    bigint                    not null,
+    url        varchar(1000)             not null,
+    changed_at timestamptz default now() not null
+);
+
+create index if not exists shortener_revision_idx_url_id on shortener_revision (url_id);
@@ -0,0 +1,3 @@
+drop index if exists shortener_idx_deleted_at;
+
+alter table shortener drop column if exists deleted_at;
@@ -0,0 +1,6 @@
+alter table shortener add column if not exists deleted_at timestamptz;
+
+-- links deleted by previous versions get migration time
+update shortener set deleted_at = now() where deleted = true and deleted_at is null;
+
+create index if not exists shortener_idx_deleted_at on shortener (deleted_at) where deleted = true;
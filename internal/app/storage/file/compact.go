@@ -0,0 +1,152 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/korol8484/shortener/internal/app/util"
+)
+
+// Stats return file size in bytes, count of records in file
+// and count of records left after compaction
+func (f *Store) Stats() (size int64, records int, live int) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.size, f.records, len(f.live)
+}
+
+// Compact rewrite file with snapshot of live state: write snapshot to temp file,
+// flush it to disk and atomically rename over storage file. Writes wait until compaction ends.
+func (f *Store) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records, err := f.snapshot()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, v := range records {
+		if err = enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	if err = util.WriteFileAtomic(f.path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	// old descriptor points to replaced file, writes to it would be lost
+	file, err := create(f.path)
+	if err != nil {
+		return err
+	}
+
+	_ = f.file.Close()
+
+	f.file = file
+	f.size = int64(buf.Len())
+	f.records = 0
+	f.live = make(map[string]struct{}, len(records))
+
+	for _, v := range records {
+		f.track(v)
+	}
+
+	return nil
+}
+
+// snapshot read file and merge records to live state: one add record per alias, every update record
+// and one tombstone per deleted alias and user. Restored tombstones and records of purged links are dropped
+func (f *Store) snapshot() ([]*storeEntity, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	var (
+		records    []*storeEntity
+		tombstones []*storeEntity
+	)
+
+	// positions of add and update records of link
+	byAlias := make(map[string][]int)
+	// live tombstone by key and keys of link tombstones
+	deleted := make(map[string]*storeEntity)
+	deletedKeys := make(map[string][]string)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		v := &storeEntity{}
+		if err = json.Unmarshal(scanner.Bytes(), v); err != nil {
+			return nil, err
+		}
+
+		switch v.Type {
+		case "", recordAdd, recordUpdate:
+			if v.Type == "" {
+				v.Type = recordAdd
+			}
+
+			// files written by previous versions may contain duplicates
+			if _, ok := byAlias[v.Alias]; ok && v.Type == recordAdd {
+				continue
+			}
+
+			// updates keep their order relative to adds, destination freed by update may be taken by later add
+			byAlias[v.Alias] = append(byAlias[v.Alias], len(records))
+			records = append(records, v)
+		case recordDelete:
+			// time of first delete is kept
+			if _, ok := deleted[v.key()]; ok {
+				continue
+			}
+
+			deleted[v.key()] = v
+			deletedKeys[v.Alias] = append(deletedKeys[v.Alias], v.key())
+			tombstones = append(tombstones, v)
+		case recordRestore:
+			delete(deleted, (&storeEntity{Type: recordDelete, Alias: v.Alias, UserID: v.UserID}).key())
+		case recordPurge:
+			for _, i := range byAlias[v.Alias] {
+				records[i] = nil
+			}
+
+			for _, key := range deletedKeys[v.Alias] {
+				delete(deleted, key)
+			}
+
+			delete(byAlias, v.Alias)
+			delete(deletedKeys, v.Alias)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	live := make([]*storeEntity, 0, len(records)+len(deleted))
+	for _, v := range records {
+		if v != nil {
+			live = append(live, v)
+		}
+	}
+
+	for _, v := range tombstones {
+		if deleted[v.key()] == v {
+			live = append(live, v)
+		}
+	}
+
+	return live, nil
+}
@@ -0,0 +1,176 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+)
+
+type compactCfg struct {
+	minSize  int64
+	ratio    float64
+	interval time.Duration
+}
+
+func (c compactCfg) GetCompactMinSize() int64 {
+	return c.minSize
+}
+
+func (c compactCfg) GetCompactRatio() float64 {
+	return c.ratio
+}
+
+func (c compactCfg) GetCompactInterval() time.Duration {
+	return c.interval
+}
+
+func countLines(t *testing.T, p string) int {
+	f, err := os.Open(p)
+	require.NoError(t, err)
+
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	var n int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+	}
+
+	return n
+}
+
+func TestStore_Compact(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	user := &domain.User{ID: 1}
+
+	_, err := store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z"},
+	}, user)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+		require.NoError(t, err)
+	}
+
+	_, records, live := store.Stats()
+	assert.Equal(t, 5, records)
+	assert.Equal(t, 3, live)
+
+	require.NoError(t, store.Compact())
+	assert.Equal(t, 3, countLines(t, dPath))
+
+	// writes after compaction go to the new file
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"}, user)
+	require.NoError(t, err)
+	assert.Equal(t, 4, countLines(t, dPath))
+
+	size, records, live := store.Stats()
+	assert.Equal(t, 4, records)
+	assert.Equal(t, 4, live)
+
+	stat, err := os.Stat(dPath)
+	require.NoError(t, err)
+	assert.Equal(t, stat.Size(), size)
+
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	userURL, err := store.ReadUserURL(context.Background(), user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 3)
+
+	for _, u := range userURL {
+		assert.Equal(t, u.Alias == "7A2S4z", u.Deleted)
+	}
+}
+
+func TestStore_Compact_Update(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Update(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "a"}, user))
+	// destination freed by update is taken by another link
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "b"}, user))
+
+	require.NoError(t, store.Compact())
+	require.NoError(t, store.Close())
+
+	store, err := NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	ent, err := store.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya1.ru", ent.URL)
+
+	ent, err = store.Read(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya.ru", ent.URL)
+
+	revisions, err := store.ReadRevisions(ctx, "a", user)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "http://www.ya.ru", revisions[0].URL)
+}
+
+func TestCompactor(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove(dPath)
+	}()
+
+	c := NewCompactor(store, compactCfg{ratio: 2}, zap.L())
+	defer c.Close()
+
+	user := &domain.User{ID: 1}
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(context.Background(), []string{"7A2S4z", "7A2S4z"}, user.ID)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, records, _ := store.Stats()
+		return records == 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CompactConfig - file storage compaction config interface
+type CompactConfig interface {
+	// GetCompactMinSize file size in bytes, smaller files are not compacted by ratio
+	GetCompactMinSize() int64
+	// GetCompactRatio compact when records in file exceed live records in ratio times, 0 - disabled
+	GetCompactRatio() float64
+	// GetCompactInterval compact by timer, 0 - disabled
+	GetCompactInterval() time.Duration
+}
+
+// Compactor runs Store compaction in background by thresholds and timer
+type Compactor struct {
+	store     *Store
+	cfg       CompactConfig
+	logger    *zap.Logger
+	closeChan chan struct{}
+	doneChan  chan struct{}
+}
+
+// NewCompactor Factory, starts background worker
+func NewCompactor(store *Store, cfg CompactConfig, logger *zap.Logger) *Compactor {
+	c := &Compactor{
+		store:     store,
+		cfg:       cfg,
+		logger:    logger,
+		closeChan: make(chan struct{}),
+		doneChan:  make(chan struct{}),
+	}
+
+	go c.process()
+
+	return c
+}
+
+// Close - stop background worker
+func (c *Compactor) Close() {
+	close(c.closeChan)
+	<-c.doneChan
+}
+
+func (c *Compactor) process() {
+	defer close(c.doneChan)
+
+	var tick <-chan time.Time
+	if c.cfg.GetCompactInterval() > 0 {
+		ticker := time.NewTicker(c.cfg.GetCompactInterval())
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			c.compact("timer")
+		case <-c.store.notify:
+			if c.exceeded() {
+				c.compact("threshold")
+			}
+		case <-c.closeChan:
+			c.logger.Info("close compaction worker")
+			return
+		}
+	}
+}
+
+func (c *Compactor) exceeded() bool {
+	ratio := c.cfg.GetCompactRatio()
+	if ratio <= 0 {
+		return false
+	}
+
+	size, records, live := c.store.Stats()
+	if size < c.cfg.GetCompactMinSize() {
+		return false
+	}
+
+	return float64(records) >= ratio*float64(max(live, 1))
+}
+
+func (c *Compactor) compact(reason string) {
+	start := time.Now()
+
+	if err := c.store.Compact(); err != nil {
+		c.logger.Error("can't compact file store", zap.String("reason", reason), zap.Error(err))
+		return
+	}
+
+	size, records, _ := c.store.Stats()
+	c.logger.Info(
+		"file store compacted",
+		zap.String("reason", reason),
+		zap.Int64("size", size),
+		zap.Int("records", records),
+		zap.Duration("ElapsedTime", time.Since(start)),
+	)
+}
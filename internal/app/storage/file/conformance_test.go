@@ -0,0 +1,22 @@
+package file
+
+import (
+	"os"
+	"testing"
+
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func TestStore_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		store, storePath := getStore(t)
+
+		t.Cleanup(func() {
+			_ = store.Close()
+			_ = os.Remove(storePath)
+		})
+
+		return &storagetest.Env{Store: store, NewUser: storagetest.UserFactory(userStorage.NewMemoryStore())}
+	})
+}
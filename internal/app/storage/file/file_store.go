@@ -2,16 +2,21 @@ package file
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/korol8484/shortener/internal/app/alias"
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/handlers"
 	"github.com/korol8484/shortener/internal/app/storage"
@@ -22,18 +27,67 @@ type Config interface {
 	GetStoragePath() string
 }
 
+// record types of JSON-lines file
+const (
+	recordAdd     = "add"
+	recordDelete  = "delete"
+	recordUpdate  = "update"
+	recordRestore = "restore"
+	recordPurge   = "purge"
+)
+
+// replayer base store methods to replay records with their original time
+type replayer interface {
+	BatchDeleteAt(ctx context.Context, aliases []string, userID int64, at time.Time) ([]string, error)
+	Remove(ctx context.Context, aliases []string) error
+}
+
+// expirer base store method returning owners of expired links to write their delete records
+type expirer interface {
+	DeleteExpiredOwned(ctx context.Context, now time.Time) (map[int64][]string, error)
+}
+
+// storeEntity one line of storage file, empty Type is add record
+// written before record types were introduced
 type storeEntity struct {
-	UUID   string `json:"uuid"`
-	Alias  string `json:"short_url"`
-	URL    string `json:"original_url"`
-	UserID int64  `json:"user_id,omitempty"`
+	UUID      string     `json:"uuid"`
+	Type      string     `json:"type,omitempty"`
+	Alias     string     `json:"short_url"`
+	URL       string     `json:"original_url,omitempty"`
+	UserID    int64      `json:"user_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (e *storeEntity) key() string {
+	switch e.Type {
+	case recordDelete:
+		return fmt.Sprintf("%s:%s:%d", recordDelete, e.Alias, e.UserID)
+	case recordUpdate, recordRestore, recordPurge:
+		// every update is revision of link, restores and purges change state of previous records,
+		// they are never merged
+		return fmt.Sprintf("%s:%s", e.Type, e.UUID)
+	}
+
+	return fmt.Sprintf("%s:%s", recordAdd, e.Alias)
 }
 
 // Store - file storage
 type Store struct {
 	mu        sync.RWMutex
+	path      string
 	file      *os.File
 	baseStore handlers.Store
+	// size of file in bytes
+	size int64
+	// records count of records in file
+	records int
+	// live keys of records which survive compaction
+	live map[string]struct{}
+	// notify signals about file changes
+	notify chan struct{}
 }
 
 // NewFileStore - file storage factory
@@ -44,14 +98,24 @@ func NewFileStore(config Config, base handlers.Store) (*Store, error) {
 	}
 
 	store := &Store{
+		path:      config.GetStoragePath(),
 		file:      file,
 		baseStore: base,
+		live:      make(map[string]struct{}),
+		notify:    make(chan struct{}, 1),
 	}
 
 	if err = store.load(); err != nil {
 		return nil, err
 	}
 
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	store.size = stat.Size()
+
 	return store, nil
 }
 
@@ -59,6 +123,8 @@ func (f *Store) load() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	ctx := context.Background()
+
 	scanner := bufio.NewScanner(f.file)
 	for scanner.Scan() {
 		v := &storeEntity{}
@@ -66,19 +132,61 @@ func (f *Store) load() error {
 			return err
 		}
 
-		if _, err := url.Parse(v.URL); err != nil {
-			return err
+		switch v.Type {
+		case "", recordAdd:
+			if _, err := url.Parse(v.URL); err != nil {
+				return err
+			}
+
+			ent := &domain.URL{URL: v.URL, Alias: v.Alias}
+			if v.ExpiresAt != nil {
+				ent.ExpiresAt = *v.ExpiresAt
+			}
+
+			// records of previous versions get load time as creation time
+			if v.CreatedAt != nil {
+				ent.CreatedAt = *v.CreatedAt
+			}
+
+			err := f.baseStore.Add(ctx, ent, &domain.User{ID: v.UserID})
+			// files written by previous versions may contain duplicates
+			if err != nil && !errors.Is(err, storage.ErrIssetURL) {
+				return err
+			}
+		case recordDelete:
+			if err := f.replayDelete(ctx, v); err != nil {
+				return err
+			}
+		case recordRestore:
+			if _, err := f.baseStore.Restore(ctx, []string{v.Alias}, v.UserID, time.Time{}); err != nil {
+				return err
+			}
+		case recordPurge:
+			r, ok := f.baseStore.(replayer)
+			if !ok {
+				return errors.New("base store can't remove links")
+			}
+
+			if err := r.Remove(ctx, []string{v.Alias}); err != nil {
+				return err
+			}
+		case recordUpdate:
+			ent := &domain.URL{URL: v.URL, Alias: v.Alias}
+			if v.UpdatedAt != nil {
+				ent.UpdatedAt = *v.UpdatedAt
+			}
+
+			if err := f.baseStore.Update(ctx, ent, &domain.User{ID: v.UserID}); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown record type: %s", v.Type)
 		}
 
-		if err := f.baseStore.Add(context.Background(), &domain.URL{
-			URL:   v.URL,
-			Alias: v.Alias,
-		}, &domain.User{ID: v.UserID}); err != nil {
-			return err
-		}
+		f.track(v)
 	}
 
-	return nil
+	return scanner.Err()
 }
 
 // Add save shorten URL
@@ -86,31 +194,68 @@ func (f *Store) Add(ctx context.Context, ent *domain.URL, user *domain.User) err
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	_, err := f.baseStore.Read(ctx, ent.Alias)
-	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+	if err := f.checkNotExists(ctx, ent); err != nil {
 		return err
 	}
 
-	if err = f.save(ent, user); err != nil {
+	if err := f.save(newAddRecord(ent, user)); err != nil {
 		return err
 	}
 
-	if err = f.baseStore.Add(ctx, ent, user); err != nil {
-		return err
-	}
-
-	return nil
+	return f.baseStore.Add(ctx, ent, user)
 }
 
-// AddBatch save shorten collection URL
-func (f *Store) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) error {
+// AddBatch save shorten collection URL, new links written to file as one append.
+// Returns outcome per link in order of batch, URL shortened before is not saved and returns existing link
+func (f *Store) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results := make([]*domain.BatchResult, 0, len(batch))
+	records := make([]*storeEntity, 0, len(batch))
+	created := make(map[string]*domain.URL, len(batch))
+	seenAlias := make(map[string]struct{}, len(batch))
+	newBatch := make(domain.BatchURL, 0, len(batch))
+
 	for _, v := range batch {
-		if err := f.Add(ctx, v, user); err != nil {
-			return err
+		if ent, ok := created[v.URL]; ok {
+			results = append(results, &domain.BatchResult{URL: ent, Status: domain.BatchItemExisting})
+			continue
+		}
+
+		ent, err := f.baseStore.ReadByURL(ctx, v.URL)
+		if err == nil {
+			results = append(results, &domain.BatchResult{URL: ent, Status: domain.BatchItemExisting})
+			continue
 		}
+
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+
+		if _, ok := seenAlias[v.Alias]; ok {
+			return nil, storage.ErrAliasTaken
+		}
+
+		if err = f.checkAliasFree(ctx, v.Alias); err != nil {
+			return nil, err
+		}
+
+		created[v.URL], seenAlias[v.Alias] = v, struct{}{}
+		records = append(records, newAddRecord(v, user))
+		newBatch = append(newBatch, v)
+		results = append(results, &domain.BatchResult{URL: v, Status: domain.BatchItemCreated})
 	}
 
-	return nil
+	if err := f.save(records...); err != nil {
+		return nil, err
+	}
+
+	if _, err := f.baseStore.AddBatch(ctx, newBatch, user); err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
 
 // ReadUserURL read user shorten URL
@@ -118,6 +263,11 @@ func (f *Store) ReadUserURL(ctx context.Context, user *domain.User) (domain.Batc
 	return f.baseStore.ReadUserURL(ctx, user)
 }
 
+// ReadUserURLPage read page of user shorten URL ordered by creation time
+func (f *Store) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	return f.baseStore.ReadUserURLPage(ctx, user, q)
+}
+
 // Read - read shorten URL
 func (f *Store) Read(ctx context.Context, alias string) (*domain.URL, error) {
 	return f.baseStore.Read(ctx, alias)
@@ -128,9 +278,207 @@ func (f *Store) ReadByURL(ctx context.Context, URL string) (*domain.URL, error)
 	return f.baseStore.ReadByURL(ctx, URL)
 }
 
-// BatchDelete delete shorten collection URL
-func (f *Store) BatchDelete(ctx context.Context, aliases []string, userID int64) error {
-	return f.baseStore.BatchDelete(ctx, aliases, userID)
+// BatchDelete delete shorten collection URL in base store, writes tombstone record per deleted alias,
+// so aliases of other users and unknown aliases leave no records. Returns deleted aliases owned by user
+func (f *Store) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deletedAt := time.Now().UTC()
+
+	var (
+		deleted []string
+		err     error
+	)
+
+	if r, ok := f.baseStore.(replayer); ok {
+		deleted, err = r.BatchDeleteAt(ctx, aliases, userID, deletedAt)
+	} else {
+		deleted, err = f.baseStore.BatchDelete(ctx, aliases, userID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*storeEntity, 0, len(deleted))
+	for _, alias := range deleted {
+		records = append(records, &storeEntity{
+			UUID:      uuid.NewString(),
+			Type:      recordDelete,
+			Alias:     alias,
+			UserID:    userID,
+			DeletedAt: &deletedAt,
+		})
+	}
+
+	return deleted, f.save(records...)
+}
+
+// Restore un-delete user shorten URL in base store, writes restore record per restored alias
+func (f *Store) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	restored, err := f.baseStore.Restore(ctx, aliases, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*storeEntity, 0, len(restored))
+	for _, alias := range restored {
+		records = append(records, &storeEntity{
+			UUID:   uuid.NewString(),
+			Type:   recordRestore,
+			Alias:  alias,
+			UserID: userID,
+		})
+	}
+
+	return restored, f.save(records...)
+}
+
+// Purge remove links deleted before given time from base store, writes purge record per alias.
+// Records of purged links are dropped by compaction
+func (f *Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.baseStore.(handlers.Purgeable)
+	if !ok {
+		return nil, errors.New("base store can't purge links")
+	}
+
+	purged, err := p.Purge(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*storeEntity, 0, len(purged))
+	for _, alias := range purged {
+		records = append(records, &storeEntity{
+			UUID:  uuid.NewString(),
+			Type:  recordPurge,
+			Alias: alias,
+		})
+	}
+
+	return purged, f.save(records...)
+}
+
+// replayDelete restore delete record with its time, records of previous versions have no time
+func (f *Store) replayDelete(ctx context.Context, v *storeEntity) error {
+	r, ok := f.baseStore.(replayer)
+	if !ok || v.DeletedAt == nil {
+		_, err := f.baseStore.BatchDelete(ctx, []string{v.Alias}, v.UserID)
+		return err
+	}
+
+	_, err := r.BatchDeleteAt(ctx, []string{v.Alias}, v.UserID, *v.DeletedAt)
+
+	return err
+}
+
+// Update change destination of user shorten URL, writes update record
+// which is replayed on load to restore destination and revision history
+func (f *Store) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, err := f.baseStore.Read(ctx, ent.Alias)
+	if err != nil {
+		return err
+	}
+
+	if current.URL == ent.URL {
+		return f.baseStore.Update(ctx, ent, user)
+	}
+
+	// set change time before base store to save the same time in file
+	if ent.UpdatedAt.IsZero() {
+		ent.UpdatedAt = time.Now().UTC()
+	}
+
+	updatedAt := ent.UpdatedAt.UTC()
+
+	// base store checks ownership and destination, record is written only for applied update
+	if err = f.baseStore.Update(ctx, ent, user); err != nil {
+		return err
+	}
+
+	return f.save(&storeEntity{
+		UUID:      uuid.NewString(),
+		Type:      recordUpdate,
+		Alias:     ent.Alias,
+		URL:       ent.URL,
+		UserID:    user.ID,
+		UpdatedAt: &updatedAt,
+	})
+}
+
+// ReadUserAlias read user shorten URL by alias
+func (f *Store) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	return f.baseStore.ReadUserAlias(ctx, alias, user)
+}
+
+// ReadRevisions read previous destinations of user shorten URL
+func (f *Store) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	return f.baseStore.ReadRevisions(ctx, alias, user)
+}
+
+// DeleteExpired soft delete expired links in base store, writes delete record per expired alias
+// with time of expiration run, so deletion time survives restart
+func (f *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	exp, ok := f.baseStore.(expirer)
+	if !ok {
+		return 0, errors.New("base store can't delete expired links")
+	}
+
+	expired, err := exp.DeleteExpiredOwned(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedAt := now.UTC()
+	owners := make([]int64, 0, len(expired))
+	for userID := range expired {
+		owners = append(owners, userID)
+	}
+
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+
+	var records []*storeEntity
+	for _, userID := range owners {
+		for _, alias := range expired[userID] {
+			records = append(records, &storeEntity{
+				UUID:      uuid.NewString(),
+				Type:      recordDelete,
+				Alias:     alias,
+				UserID:    userID,
+				DeletedAt: &deletedAt,
+			})
+		}
+	}
+
+	return int64(len(records)), f.save(records...)
+}
+
+// NextID return next alias sequence value from base store
+func (f *Store) NextID(ctx context.Context) (int64, error) {
+	seq, ok := f.baseStore.(alias.Sequence)
+	if !ok {
+		return 0, errors.New("base store has no sequence")
+	}
+
+	return seq.NextID(ctx)
+}
+
+// CountURL return count of stored shorten URL from base store
+func (f *Store) CountURL(ctx context.Context) (int64, error) {
+	return f.baseStore.CountURL(ctx)
 }
 
 // Close - close file store
@@ -138,30 +486,103 @@ func (f *Store) Close() error {
 	return f.file.Close()
 }
 
-func (f *Store) save(ent *domain.URL, user *domain.User) error {
-	v := &storeEntity{
-		UUID:   uuid.NewString(),
-		Alias:  ent.Alias,
-		URL:    ent.URL,
-		UserID: user.ID,
+// save append records to file with one write and flush it to disk
+func (f *Store) save(records ...*storeEntity) error {
+	if len(records) == 0 {
+		return nil
 	}
 
-	b, err := json.Marshal(&v)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, v := range records {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(buf.Bytes())
+	f.size += int64(n)
 	if err != nil {
 		return err
 	}
 
-	if _, err = f.file.Write(b); err != nil {
+	if err = f.file.Sync(); err != nil {
 		return err
 	}
 
-	if _, err = f.file.Write([]byte("\n")); err != nil {
+	for _, v := range records {
+		f.track(v)
+	}
+
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// track count record and remember its key, records with the same key are merged by compaction
+func (f *Store) track(v *storeEntity) {
+	f.records++
+	f.live[v.key()] = struct{}{}
+}
+
+// checkNotExists returns storage.ErrIssetURL when URL saved before
+// and storage.ErrAliasTaken when alias belongs to another URL
+func (f *Store) checkNotExists(ctx context.Context, ent *domain.URL) error {
+	_, err := f.baseStore.ReadByURL(ctx, ent.URL)
+	if err == nil {
+		return storage.ErrIssetURL
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	return f.checkAliasFree(ctx, ent.Alias)
+}
+
+// checkAliasFree returns storage.ErrAliasTaken when alias belongs to another URL
+func (f *Store) checkAliasFree(ctx context.Context, alias string) error {
+	_, err := f.baseStore.Read(ctx, alias)
+	if err == nil {
+		return storage.ErrAliasTaken
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
 		return err
 	}
 
 	return nil
 }
 
+// newAddRecord create add record, sets creation time of entity to save the same time in file and base store
+func newAddRecord(ent *domain.URL, user *domain.User) *storeEntity {
+	if ent.CreatedAt.IsZero() {
+		ent.CreatedAt = time.Now().UTC()
+	}
+
+	createdAt := ent.CreatedAt.UTC()
+
+	rec := &storeEntity{
+		UUID:      uuid.NewString(),
+		Type:      recordAdd,
+		Alias:     ent.Alias,
+		URL:       ent.URL,
+		UserID:    user.ID,
+		CreatedAt: &createdAt,
+	}
+
+	if !ent.ExpiresAt.IsZero() {
+		expiresAt := ent.ExpiresAt.UTC()
+		rec.ExpiresAt = &expiresAt
+	}
+
+	return rec
+}
+
 func create(p string) (*os.File, error) {
 	if err := os.MkdirAll(filepath.Dir(p), 0770); err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -53,7 +54,7 @@ func TestStore_Add(t *testing.T) {
 	}, &domain.User{ID: 1})
 	require.ErrorIs(t, storage.ErrIssetURL, err)
 
-	err = store.AddBatch(context.Background(), domain.BatchURL{
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
 		&domain.URL{
 			URL:   "http://www.ya1.ru",
 			Alias: "7A1S4z",
@@ -61,13 +62,15 @@ func TestStore_Add(t *testing.T) {
 	}, &domain.User{ID: 1})
 	require.NoError(t, err)
 
-	err = store.AddBatch(context.Background(), domain.BatchURL{
+	results, err := store.AddBatch(context.Background(), domain.BatchURL{
 		&domain.URL{
 			URL:   "http://www.ya1.ru",
-			Alias: "7A1S4z",
+			Alias: "7A1S5z",
 		},
 	}, &domain.User{ID: 1})
-	require.ErrorIs(t, storage.ErrIssetURL, err)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BatchItemExisting, results[0].Status)
+	assert.Equal(t, "7A1S4z", results[0].URL.Alias)
 }
 
 func TestStore_Read(t *testing.T) {
@@ -171,6 +174,22 @@ func TestStore_ReadByURL(t *testing.T) {
 	require.ErrorIs(t, err, storage.ErrNotFound)
 }
 
+func TestStore_CountURL(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove(dPath)
+	}()
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	n, err := store.CountURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
 func TestStore_BatchDelete(t *testing.T) {
 	store, dPath := getStore(t)
 
@@ -184,7 +203,7 @@ func TestStore_BatchDelete(t *testing.T) {
 	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
 	require.NoError(t, err)
 
-	err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+	_, err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
 	require.NoError(t, err)
 
 	userURL, err := store.ReadUserURL(context.Background(), user)
@@ -199,7 +218,7 @@ func TestStore_BatchDelete(t *testing.T) {
 func TestStore_save(t *testing.T) {
 	store, dPath := getStore(t)
 
-	err := store.save(&domain.URL{URL: "1", Alias: "1"}, &domain.User{ID: 1})
+	err := store.save(newAddRecord(&domain.URL{URL: "1", Alias: "1"}, &domain.User{ID: 1}))
 	require.NoError(t, err)
 
 	err = store.Close()
@@ -211,11 +230,228 @@ func TestStore_save(t *testing.T) {
 	_ = os.Remove(dPath)
 	_ = store.Close()
 
-	err = store.save(&domain.URL{URL: "1", Alias: "1"}, &domain.User{ID: 1})
+	err = store.save(newAddRecord(&domain.URL{URL: "1", Alias: "1"}, &domain.User{ID: 1}))
 	require.Error(t, err)
 }
 
 func TestStore_AddBatch(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	user := &domain.User{ID: 1}
+
+	_, err := store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z"},
+	}, user)
+	require.NoError(t, err)
+
+	results, err := store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A4S4z"},
+	}, user)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BatchItemCreated, results[0].Status)
+	assert.Equal(t, domain.BatchItemExisting, results[1].Status)
+	assert.Equal(t, "7A2S4z", results[1].URL.Alias)
+
+	_, err = store.Read(context.Background(), "7A4S4z")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	userURL, err := store.ReadUserURL(context.Background(), user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 3)
+}
+
+func TestStore_AliasTaken(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = store.Close()
+		_ = os.Remove(dPath)
+	}()
+
+	user := &domain.User{ID: 1}
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "7A2S4z"}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+		&domain.URL{URL: "http://www.ya3.ru", Alias: "7A3S4z"},
+	}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	id, err := store.NextID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
+
+func TestStore_DeleteExpired(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	now := time.Now()
+
+	err := store.Add(context.Background(), &domain.URL{
+		URL: "http://www.ya.ru", Alias: "7A2S4z", ExpiresAt: now.Add(time.Minute),
+	}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	n, err := store.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	ent, err := store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.True(t, now.Add(time.Minute).Equal(ent.ExpiresAt))
+
+	n, err = store.DeleteExpired(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	// expiration is written as delete record with its time, link is purged after restart
+	purged, err := store.Purge(context.Background(), now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"7A2S4z"}, purged)
+}
+
+func TestStore_BatchDelete_Restart(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	user := &domain.User{ID: 1}
+
+	_, err := store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z"},
+	}, user)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	userURL, err := store.ReadUserURL(context.Background(), user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 2)
+
+	for _, u := range userURL {
+		assert.Equal(t, u.Alias == "7A2S4z", u.Deleted)
+	}
+}
+
+func TestStore_BatchDelete_ForeignAlias(t *testing.T) {
+	ctx := context.Background()
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	owner := &domain.User{ID: 1}
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "own"}, owner))
+
+	// alias of another user
+	deleted, err := store.BatchDelete(ctx, []string{"own"}, 2)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	// alias not created yet, compaction moves tombstones after add records
+	deleted, err = store.BatchDelete(ctx, []string{"later"}, owner.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "later"}, owner))
+
+	for _, compact := range []bool{false, true} {
+		if compact {
+			require.NoError(t, store.Compact())
+		}
+
+		require.NoError(t, store.Close())
+
+		store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+		require.NoError(t, err)
+
+		for _, alias := range []string{"own", "later"} {
+			u, rErr := store.Read(ctx, alias)
+			require.NoError(t, rErr)
+			assert.False(t, u.Deleted, alias)
+		}
+	}
+
+	require.NoError(t, store.Close())
+}
+
+func TestStore_ReadUserURLPage_Restart(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	user := &domain.User{ID: 1}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z", CreatedAt: created}, user)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	page, err := store.ReadUserURLPage(context.Background(), user, &domain.UserURLQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, created.Equal(page.Items[0].CreatedAt))
 }
 
 func Test_load(t *testing.T) {
@@ -227,7 +463,13 @@ func Test_load(t *testing.T) {
 		_ = os.Remove(name)
 	}(p)
 
-	_, err = f.Write([]byte("{\"uuid\":\"52edec03-edee-4600-999f-f5af452c29f0\",\"short_url\":\"7qfJga\",\"original_url\":\"http://www.ya1111111sdfdsfcccccfsdc11.ru\",\"user_id\":8}"))
+	_, err = f.Write([]byte("{\"uuid\":\"52edec03-edee-4600-999f-f5af452c29f0\",\"short_url\":\"7qfJga\",\"original_url\":\"http://www.ya1111111sdfdsfcccccfsdc11.ru\",\"user_id\":8}\n"))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("{\"uuid\":\"52edec03-edee-4600-999f-f5af452c29f1\",\"short_url\":\"7qfJga\",\"original_url\":\"http://www.ya1111111sdfdsfcccccfsdc11.ru\",\"user_id\":8}\n"))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("{\"uuid\":\"52edec03-edee-4600-999f-f5af452c29f2\",\"type\":\"delete\",\"short_url\":\"7qfJga\",\"user_id\":8}"))
 	require.NoError(t, err)
 
 	_ = f.Close()
@@ -235,6 +477,107 @@ func Test_load(t *testing.T) {
 	store, err := NewFileStore(StoreCfg(p), memory.NewMemStore())
 	require.NoError(t, err)
 
+	userURL, err := store.ReadUserURL(context.Background(), &domain.User{ID: 8})
+	require.NoError(t, err)
+	require.Len(t, userURL, 1)
+	assert.True(t, userURL[0].Deleted)
+
 	err = store.Close()
 	require.NoError(t, err)
 }
+
+func TestStore_RestorePurge_Restart(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	_, err := store.AddBatch(ctx, domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "a"},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "b"},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "c"},
+	}, user)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(ctx, []string{"a", "b", "c"}, user.ID)
+	require.NoError(t, err)
+
+	restored, err := store.Restore(ctx, []string{"a"}, user.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, restored)
+
+	purged, err := store.Purge(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, purged)
+
+	// alias of purged link is taken again
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya3.ru", Alias: "b"}, user))
+
+	check := func(store *Store) {
+		userURL, rErr := store.ReadUserURL(ctx, user)
+		require.NoError(t, rErr)
+		require.Len(t, userURL, 2)
+
+		for _, u := range userURL {
+			assert.False(t, u.Deleted, u.Alias)
+		}
+
+		ent, rErr := store.Read(ctx, "b")
+		require.NoError(t, rErr)
+		assert.Equal(t, "http://www.ya3.ru", ent.URL)
+
+		_, rErr = store.Read(ctx, "c")
+		require.ErrorIs(t, rErr, storage.ErrNotFound)
+	}
+
+	check(store)
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+	check(store)
+
+	// purged and restored records are dropped
+	require.NoError(t, store.Compact())
+	_, records, _ := store.Stats()
+	assert.Equal(t, 2, records)
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+	check(store)
+	require.NoError(t, store.Close())
+}
+
+func TestStore_BatchDelete_KeepsTime(t *testing.T) {
+	store, dPath := getStore(t)
+
+	defer func() {
+		_ = os.Remove(dPath)
+	}()
+
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+
+	_, err := store.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(StoreCfg(dPath), memory.NewMemStore())
+	require.NoError(t, err)
+
+	defer func() {
+		_ = store.Close()
+	}()
+
+	// delete time is restored from file, not from load time
+	restored, err := store.Restore(ctx, []string{"a"}, user.ID, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+}
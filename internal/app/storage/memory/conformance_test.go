@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func TestMemStore_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		return &storagetest.Env{Store: NewMemStore(), NewUser: storagetest.UserFactory(userStorage.NewMemoryStore())}
+	})
+}
@@ -2,31 +2,41 @@ package memory
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/korol8484/shortener/internal/app/domain"
 	"github.com/korol8484/shortener/internal/app/storage"
 )
 
 type item struct {
-	URL     string
-	deleted bool
+	URL       string
+	owner     int64
+	deleted   bool
+	deletedAt time.Time
+	expiresAt time.Time
+	createdAt time.Time
 }
 
 // MemStore - in memory shorten links storage
 type MemStore struct {
-	mu           sync.RWMutex
-	items        map[string]item
-	userItems    map[int64][]string
-	deletedItems map[int64]map[string]interface{}
+	mu        sync.RWMutex
+	items     map[string]item
+	urls      map[string]string
+	seq       int64
+	userItems map[int64][]string
+	revisions map[string][]*domain.Revision
 }
 
 // NewMemStore in memory shorten links storage factory
 func NewMemStore() *MemStore {
 	store := &MemStore{
-		items:        make(map[string]item),
-		userItems:    make(map[int64][]string),
-		deletedItems: make(map[int64]map[string]interface{}),
+		items:     make(map[string]item),
+		urls:      make(map[string]string),
+		userItems: make(map[int64][]string),
+		revisions: make(map[string][]*domain.Revision),
 	}
 
 	return store
@@ -37,25 +47,52 @@ func (m *MemStore) Add(ctx context.Context, ent *domain.URL, user *domain.User)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.items[ent.Alias]; ok {
-		return storage.ErrIssetURL
+	if err := m.check(ent); err != nil {
+		return err
 	}
 
-	m.items[ent.Alias] = item{URL: ent.URL}
-	m.userItems[user.ID] = append(m.userItems[user.ID], ent.Alias)
+	m.add(ent, user)
 
 	return nil
 }
 
-// AddBatch save shorten collection URL
-func (m *MemStore) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) error {
+// AddBatch save shorten collection URL, returns outcome per link in order of batch.
+// URL shortened before, also earlier in the same batch, is not saved and returns existing link.
+// Nothing saved when alias of one of new links is taken
+func (m *MemStore) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]*domain.BatchResult, 0, len(batch))
+	created := make(map[string]*domain.URL, len(batch))
+	seenAlias := make(map[string]struct{}, len(batch))
+
 	for _, v := range batch {
-		if err := m.Add(ctx, v, user); err != nil {
-			return err
+		if ent, ok := created[v.URL]; ok {
+			results = append(results, &domain.BatchResult{URL: ent, Status: domain.BatchItemExisting})
+			continue
 		}
+
+		if alias, ok := m.urls[v.URL]; ok {
+			results = append(results, &domain.BatchResult{URL: m.entity(alias), Status: domain.BatchItemExisting})
+			continue
+		}
+
+		if _, ok := seenAlias[v.Alias]; ok || m.hasAlias(v.Alias) {
+			return nil, storage.ErrAliasTaken
+		}
+
+		created[v.URL], seenAlias[v.Alias] = v, struct{}{}
+		results = append(results, &domain.BatchResult{URL: v, Status: domain.BatchItemCreated})
 	}
 
-	return nil
+	for _, v := range results {
+		if v.Status == domain.BatchItemCreated {
+			m.add(v.URL, user)
+		}
+	}
+
+	return results, nil
 }
 
 // ReadUserURL read user shorten URL
@@ -70,20 +107,15 @@ func (m *MemStore) ReadUserURL(ctx context.Context, user *domain.User) (domain.B
 		return batch, nil
 	}
 
-	deleted, hasDel := m.deletedItems[user.ID]
-
 	for _, alias := range aliases {
 		u := m.items[alias]
 
 		URL := &domain.URL{
-			URL:   u.URL,
-			Alias: alias,
-		}
-
-		if hasDel {
-			if _, ok := deleted[alias]; ok {
-				URL.Deleted = true
-			}
+			URL:       u.URL,
+			Alias:     alias,
+			Deleted:   u.deleted,
+			ExpiresAt: u.expiresAt,
+			CreatedAt: u.createdAt,
 		}
 
 		batch = append(batch, URL)
@@ -92,6 +124,52 @@ func (m *MemStore) ReadUserURL(ctx context.Context, user *domain.User) (domain.B
 	return batch, nil
 }
 
+// ReadUserURLPage read page of user shorten URL ordered by creation time
+func (m *MemStore) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	batch, err := m.ReadUserURL(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.UserURLPage{Items: make(domain.BatchURL, 0, q.Limit)}
+
+	// before reports a goes before b in requested order
+	before := func(a, b *domain.Cursor) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt) != q.Desc
+		}
+
+		return (a.Alias < b.Alias) != q.Desc
+	}
+
+	sort.Slice(batch, func(i, j int) bool {
+		return before(domain.CursorOf(batch[i]), domain.CursorOf(batch[j]))
+	})
+
+	for _, u := range batch {
+		if u.Deleted && !q.IncludeDeleted {
+			continue
+		}
+
+		if !strings.Contains(u.URL, q.Contains) {
+			continue
+		}
+
+		if q.After != nil && !before(q.After, domain.CursorOf(u)) {
+			continue
+		}
+
+		if len(page.Items) == q.Limit {
+			page.Next = domain.CursorOf(page.Items[len(page.Items)-1])
+			break
+		}
+
+		page.Items = append(page.Items, u)
+	}
+
+	return page, nil
+}
+
 // Read - read shorten URL
 func (m *MemStore) Read(ctx context.Context, alias string) (*domain.URL, error) {
 	m.mu.RLock()
@@ -101,7 +179,7 @@ func (m *MemStore) Read(ctx context.Context, alias string) (*domain.URL, error)
 		return nil, storage.ErrNotFound
 	}
 
-	return &domain.URL{Alias: alias, URL: m.items[alias].URL, Deleted: m.items[alias].deleted}, nil
+	return m.entity(alias), nil
 }
 
 // ReadByURL read shorten URL by URL
@@ -109,39 +187,320 @@ func (m *MemStore) ReadByURL(ctx context.Context, URL string) (*domain.URL, erro
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for k, v := range m.items {
-		if v.URL == URL {
-			return &domain.URL{
-				URL:   v.URL,
-				Alias: k,
-			}, nil
-		}
+	alias, ok := m.urls[URL]
+	if !ok {
+		return nil, storage.ErrNotFound
 	}
 
-	return nil, storage.ErrNotFound
+	return m.entity(alias), nil
+}
+
+// BatchDelete delete shorten collection URL, returns deleted aliases owned by user
+func (m *MemStore) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	return m.BatchDeleteAt(ctx, aliases, userID, time.Now())
 }
 
-// BatchDelete delete shorten collection URL
-func (m *MemStore) BatchDelete(ctx context.Context, aliases []string, userID int64) error {
+// BatchDeleteAt delete shorten collection URL at given time, links of other users are skipped
+// and time of first delete is kept. Used to restore deletes from file with their original time
+func (m *MemStore) BatchDeleteAt(ctx context.Context, aliases []string, userID int64, at time.Time) ([]string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.deletedItems[userID]; !ok {
-		m.deletedItems[userID] = make(map[string]interface{}, len(aliases))
+	deleted := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		if !m.isOwner(alias, userID) {
+			continue
+		}
+
+		if u := m.items[alias]; !u.deleted {
+			u.deleted, u.deletedAt = true, at
+			m.items[alias] = u
+		}
+
+		deleted = append(deleted, alias)
 	}
 
+	return deleted, nil
+}
+
+// Restore un-delete user shorten URL deleted since given time, expired links are not restored.
+// Returns restored aliases
+func (m *MemStore) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	restored := make([]string, 0, len(aliases))
+
 	for _, alias := range aliases {
-		m.deletedItems[userID][alias] = nil
+		if !m.isOwner(alias, userID) {
+			continue
+		}
+
+		u := m.items[alias]
+		if !u.deleted || u.deletedAt.Before(since) {
+			continue
+		}
+
+		if !u.expiresAt.IsZero() && !now.Before(u.expiresAt) {
+			continue
+		}
+
+		u.deleted, u.deletedAt = false, time.Time{}
+		m.items[alias] = u
+
+		restored = append(restored, alias)
 	}
 
+	return restored, nil
+}
+
+// Purge remove links deleted before given time, returns removed aliases
+func (m *MemStore) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged []string
+	for alias, u := range m.items {
+		if u.deleted && u.deletedAt.Before(before) {
+			purged = append(purged, alias)
+		}
+	}
+
+	sort.Strings(purged)
+	m.remove(purged)
+
+	return purged, nil
+}
+
+// Remove physically remove links. Used to restore purges from file
+func (m *MemStore) Remove(ctx context.Context, aliases []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(aliases)
+
 	return nil
 }
 
+// Update change destination of user shorten URL and save previous destination as revision.
+// Returns storage.ErrNotFound when link is not owned by user or deleted
+// and storage.ErrIssetURL when new destination is shortened by another link
+func (m *MemStore) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.owns(ent.Alias, user.ID) {
+		return storage.ErrNotFound
+	}
+
+	u := m.items[ent.Alias]
+	if u.URL == ent.URL {
+		return nil
+	}
+
+	if _, ok := m.urls[ent.URL]; ok {
+		return storage.ErrIssetURL
+	}
+
+	// change time is kept when update is restored from file
+	if ent.UpdatedAt.IsZero() {
+		ent.UpdatedAt = time.Now()
+	}
+
+	m.revisions[ent.Alias] = append(m.revisions[ent.Alias], &domain.Revision{
+		Alias:     ent.Alias,
+		URL:       u.URL,
+		UserID:    user.ID,
+		ChangedAt: ent.UpdatedAt,
+	})
+
+	delete(m.urls, u.URL)
+	m.urls[ent.URL] = ent.Alias
+
+	u.URL = ent.URL
+	m.items[ent.Alias] = u
+
+	return nil
+}
+
+// ReadUserAlias read user shorten URL by alias, deleted links are read too.
+// Returns storage.ErrNotFound when link is not owned by user
+func (m *MemStore) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.isOwner(alias, user.ID) {
+		return nil, storage.ErrNotFound
+	}
+
+	return m.entity(alias), nil
+}
+
+// ReadRevisions read previous destinations of user shorten URL in order of change
+func (m *MemStore) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.owns(alias, user.ID) {
+		return nil, storage.ErrNotFound
+	}
+
+	revisions := make([]*domain.Revision, 0, len(m.revisions[alias]))
+	for _, v := range m.revisions[alias] {
+		rev := *v
+		revisions = append(revisions, &rev)
+	}
+
+	return revisions, nil
+}
+
+// DeleteExpired soft delete links expired at time now, returns count of deleted links
+func (m *MemStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	deleted, err := m.DeleteExpiredOwned(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, aliases := range deleted {
+		n += int64(len(aliases))
+	}
+
+	return n, nil
+}
+
+// DeleteExpiredOwned soft delete links expired at time now, returns deleted aliases by owner
+func (m *MemStore) DeleteExpiredOwned(ctx context.Context, now time.Time) (map[int64][]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := make(map[int64][]string)
+	for alias, u := range m.items {
+		if u.deleted || u.expiresAt.IsZero() || now.Before(u.expiresAt) {
+			continue
+		}
+
+		u.deleted, u.deletedAt = true, now
+		m.items[alias] = u
+		deleted[u.owner] = append(deleted[u.owner], alias)
+	}
+
+	for _, aliases := range deleted {
+		sort.Strings(aliases)
+	}
+
+	return deleted, nil
+}
+
+// NextID return next alias sequence value, never less than count of stored links
+func (m *MemStore) NextID(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seq++
+	if m.seq <= int64(len(m.items)) {
+		m.seq = int64(len(m.items)) + 1
+	}
+
+	return m.seq, nil
+}
+
+// CountURL return count of stored shorten URL
+func (m *MemStore) CountURL(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.items)), nil
+}
+
 // Close - clear store resources
 func (m *MemStore) Close() error {
 	return nil
 }
 
+// check returns storage.ErrIssetURL when URL saved before
+// and storage.ErrAliasTaken when alias belongs to another URL
+func (m *MemStore) check(ent *domain.URL) error {
+	if _, ok := m.urls[ent.URL]; ok {
+		return storage.ErrIssetURL
+	}
+
+	if m.hasAlias(ent.Alias) {
+		return storage.ErrAliasTaken
+	}
+
+	return nil
+}
+
+// entity link saved with alias
+func (m *MemStore) entity(alias string) *domain.URL {
+	u := m.items[alias]
+
+	return &domain.URL{Alias: alias, URL: u.URL, Deleted: u.deleted, ExpiresAt: u.expiresAt, CreatedAt: u.createdAt}
+}
+
+func (m *MemStore) add(ent *domain.URL, user *domain.User) {
+	// creation time is kept when link is restored from file
+	if ent.CreatedAt.IsZero() {
+		ent.CreatedAt = time.Now()
+	}
+
+	m.items[ent.Alias] = item{URL: ent.URL, owner: user.ID, expiresAt: ent.ExpiresAt, createdAt: ent.CreatedAt}
+	m.urls[ent.URL] = ent.Alias
+	m.userItems[user.ID] = append(m.userItems[user.ID], ent.Alias)
+}
+
+// owns check link belongs to user and is not deleted
+func (m *MemStore) owns(alias string, userID int64) bool {
+	if m.items[alias].deleted {
+		return false
+	}
+
+	return m.isOwner(alias, userID)
+}
+
+// isOwner check link belongs to user
+func (m *MemStore) isOwner(alias string, userID int64) bool {
+	u, ok := m.items[alias]
+
+	return ok && u.owner == userID
+}
+
+// remove drop links with their owners and revisions
+func (m *MemStore) remove(aliases []string) {
+	if len(aliases) == 0 {
+		return
+	}
+
+	removed := make(map[string]struct{}, len(aliases))
+	for _, alias := range aliases {
+		u, ok := m.items[alias]
+		if !ok {
+			continue
+		}
+
+		if m.urls[u.URL] == alias {
+			delete(m.urls, u.URL)
+		}
+
+		delete(m.items, alias)
+		delete(m.revisions, alias)
+		removed[alias] = struct{}{}
+	}
+
+	for userID, list := range m.userItems {
+		kept := list[:0]
+		for _, alias := range list {
+			if _, ok := removed[alias]; !ok {
+				kept = append(kept, alias)
+			}
+		}
+
+		m.userItems[userID] = kept
+	}
+}
+
 func (m *MemStore) hasAlias(alias string) bool {
 	if _, ok := m.items[alias]; ok {
 		return ok
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -31,7 +32,7 @@ func TestMemStore_Add(t *testing.T) {
 	}, &domain.User{ID: 1})
 	require.ErrorIs(t, storage.ErrIssetURL, err)
 
-	err = store.AddBatch(context.Background(), domain.BatchURL{
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
 		&domain.URL{
 			URL:   "http://www.ya1.ru",
 			Alias: "7A1S4z",
@@ -39,13 +40,15 @@ func TestMemStore_Add(t *testing.T) {
 	}, &domain.User{ID: 1})
 	require.NoError(t, err)
 
-	err = store.AddBatch(context.Background(), domain.BatchURL{
+	results, err := store.AddBatch(context.Background(), domain.BatchURL{
 		&domain.URL{
 			URL:   "http://www.ya1.ru",
-			Alias: "7A1S4z",
+			Alias: "7A1S5z",
 		},
 	}, &domain.User{ID: 1})
-	require.ErrorIs(t, storage.ErrIssetURL, err)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BatchItemExisting, results[0].Status)
+	assert.Equal(t, "7A1S4z", results[0].URL.Alias)
 }
 
 func TestMemStore_Read(t *testing.T) {
@@ -126,6 +129,98 @@ func TestMemStore_ReadUserURL(t *testing.T) {
 	require.Len(t, userURL, 0)
 }
 
+func TestMemStore_CountURL(t *testing.T) {
+	store := NewMemStore()
+
+	n, err := store.CountURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	n, err = store.CountURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestMemStore_ReadUserURLPage(t *testing.T) {
+	store := NewMemStore()
+	user := &domain.User{ID: 1}
+	created := time.Now()
+
+	for i, v := range []string{"c", "a", "b"} {
+		err := store.Add(context.Background(), &domain.URL{
+			URL: "http://ya.ru/" + v, Alias: v, CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}, user)
+		require.NoError(t, err)
+	}
+
+	page, err := store.ReadUserURLPage(context.Background(), user, &domain.UserURLQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "c", page.Items[0].Alias)
+	assert.Equal(t, "a", page.Items[1].Alias)
+	require.NotNil(t, page.Next)
+
+	page, err = store.ReadUserURLPage(context.Background(), user, &domain.UserURLQuery{Limit: 2, After: page.Next})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "b", page.Items[0].Alias)
+	assert.Nil(t, page.Next)
+
+	_, err = store.BatchDelete(context.Background(), []string{"b"}, user.ID)
+	require.NoError(t, err)
+
+	page, err = store.ReadUserURLPage(context.Background(), user, &domain.UserURLQuery{Limit: 5, Desc: true})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "a", page.Items[0].Alias)
+
+	page, err = store.ReadUserURLPage(context.Background(), user, &domain.UserURLQuery{Limit: 5, Contains: "/b", IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.Items[0].Deleted)
+}
+
+func TestMemStore_Update(t *testing.T) {
+	store := NewMemStore()
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+
+	require.NoError(t, store.Update(ctx, &domain.URL{URL: "http://www.ya2.ru", Alias: "a"}, user))
+
+	ent, err := store.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya2.ru", ent.URL)
+
+	_, err = store.ReadByURL(ctx, "http://www.ya.ru")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	err = store.Update(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "a"}, user)
+	require.ErrorIs(t, err, storage.ErrIssetURL)
+
+	err = store.Update(ctx, &domain.URL{URL: "http://www.ya3.ru", Alias: "a"}, &domain.User{ID: 2})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	revisions, err := store.ReadRevisions(ctx, "a", user)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "http://www.ya.ru", revisions[0].URL)
+
+	_, err = store.ReadRevisions(ctx, "a", &domain.User{ID: 2})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = store.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+
+	err = store.Update(ctx, &domain.URL{URL: "http://www.ya3.ru", Alias: "a"}, user)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
 func TestMemStore_ReadByURL(t *testing.T) {
 	store := NewMemStore()
 	defer func(store handlers.Store) {
@@ -156,8 +251,9 @@ func TestMemStore_BatchDelete(t *testing.T) {
 	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
 	require.NoError(t, err)
 
-	err = store.BatchDelete(context.Background(), []string{"7A2S4z"}, user.ID)
+	deleted, err := store.BatchDelete(context.Background(), []string{"7A2S4z", "unknown"}, user.ID)
 	require.NoError(t, err)
+	assert.Equal(t, []string{"7A2S4z"}, deleted)
 
 	userURL, err := store.ReadUserURL(context.Background(), user)
 	require.NoError(t, err)
@@ -167,3 +263,115 @@ func TestMemStore_BatchDelete(t *testing.T) {
 	assert.Equal(t, "7A2S4z", userURL[0].Alias)
 	assert.Equal(t, true, userURL[0].Deleted)
 }
+
+func TestMemStore_AliasTaken(t *testing.T) {
+	store := NewMemStore()
+
+	err := store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "7A2S4z"}, &domain.User{ID: 1})
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+		&domain.URL{URL: "http://www.ya3.ru", Alias: "7A2S4z"},
+	}, &domain.User{ID: 1})
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = store.ReadByURL(context.Background(), "http://www.ya2.ru")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	id, err := store.NextID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
+
+func TestMemStore_DeleteExpired(t *testing.T) {
+	store := NewMemStore()
+	now := time.Now()
+
+	_, err := store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z", ExpiresAt: now.Add(-time.Minute)},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z", ExpiresAt: now.Add(time.Hour)},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+	}, &domain.User{ID: 1})
+	require.NoError(t, err)
+
+	n, err := store.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	ent, err := store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+
+	ent, err = store.Read(context.Background(), "7A1S4z")
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+	assert.Equal(t, now.Add(time.Hour), ent.ExpiresAt)
+
+	n, err = store.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestMemStore_Restore(t *testing.T) {
+	store := NewMemStore()
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+	deletedAt := time.Now().Add(-time.Hour)
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya2.ru", Alias: "c"}, &domain.User{ID: 2}))
+
+	_, err := store.BatchDeleteAt(ctx, []string{"a", "b"}, user.ID, deletedAt)
+	require.NoError(t, err)
+	_, err = store.BatchDelete(ctx, []string{"c"}, 2)
+	require.NoError(t, err)
+
+	// b is deleted before window, c belongs to another user
+	restored, err := store.Restore(ctx, []string{"a", "c"}, user.ID, deletedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, restored)
+
+	restored, err = store.Restore(ctx, []string{"b"}, user.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	require.NoError(t, store.Update(ctx, &domain.URL{URL: "http://www.ya.ru/new", Alias: "a"}, user))
+}
+
+func TestMemStore_Purge(t *testing.T) {
+	store := NewMemStore()
+	ctx := context.Background()
+	user := &domain.User{ID: 1}
+	now := time.Now()
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya2.ru", Alias: "c", ExpiresAt: now.Add(-3 * time.Hour)}, user))
+
+	_, err := store.BatchDeleteAt(ctx, []string{"a"}, user.ID, now.Add(-2*time.Hour))
+	require.NoError(t, err)
+	_, err = store.BatchDelete(ctx, []string{"b"}, user.ID)
+	require.NoError(t, err)
+	_, err = store.DeleteExpired(ctx, now.Add(-90*time.Minute))
+	require.NoError(t, err)
+
+	purged, err := store.Purge(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, purged)
+
+	_, err = store.Read(ctx, "a")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	userURL, err := store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 1)
+	assert.Equal(t, "b", userURL[0].Alias)
+
+	// alias and URL of purged link are free
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+}
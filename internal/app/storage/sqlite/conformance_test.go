@@ -0,0 +1,15 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+)
+
+func TestStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		store := getStore(t)
+
+		return &storagetest.Env{Store: store, NewUser: storagetest.UserFactory(store)}
+	})
+}
@@ -0,0 +1,5 @@
+drop table if exists shortener_revision;
+drop table if exists user_url;
+drop table if exists alias_sequence;
+drop table if exists shortener;
+drop table if exists "user";
@@ -0,0 +1,63 @@
+-- tables of previous versions are kept, schema of them is the same
+create table if not exists "user"
+(
+    id integer not null
+        constraint user_pk
+            primary key autoincrement
+);
+
+create table if not exists shortener
+(
+    id         integer not null
+        constraint shortener_pk
+            primary key autoincrement,
+    url        text    not null,
+    alias      text    not null,
+    deleted    integer default 0 not null,
+    expires_at datetime,
+    created_at datetime,
+    deleted_at datetime
+);
+
+create index if not exists shortener_idx_expires_at on shortener (expires_at);
+create index if not exists shortener_idx_created_at_alias on shortener (created_at, alias);
+create index if not exists shortener_idx_deleted_at on shortener (deleted_at) where deleted = 1;
+create unique index if not exists shortener_uidx_alias on shortener (alias);
+create unique index if not exists shortener_uidx_url on shortener (url);
+
+create table if not exists alias_sequence
+(
+    id integer not null
+        constraint alias_sequence_pk
+            primary key autoincrement
+);
+
+create table if not exists user_url
+(
+    user_id integer not null
+        constraint user_url_user_id_fk
+            references "user"
+            on delete cascade,
+    url_id  integer not null
+        constraint user_url_shortener_id_fk
+            references shortener
+            on delete cascade
+);
+
+create unique index if not exists user_url_url_id_user_id_uindex on user_url (url_id, user_id);
+
+create table if not exists shortener_revision
+(
+    id         integer  not null
+        constraint shortener_revision_pk
+            primary key autoincrement,
+    url_id     integer  not null
+        constraint shortener_revision_shortener_id_fk
+            references shortener
+            on delete cascade,
+    user_id    integer  not null,
+    url        text     not null,
+    changed_at datetime not null
+);
+
+create index if not exists shortener_revision_idx_url_id on shortener_revision (url_id);
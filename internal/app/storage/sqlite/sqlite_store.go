@@ -0,0 +1,549 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+const (
+	queryRead      = "SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t WHERE alias = ?"
+	queryReadByURL = "SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t WHERE url = ?"
+	queryReadUser  = "SELECT t.url, t.alias, t.deleted, t.expires_at FROM shortener t INNER JOIN user_url uu on t.id = uu.url_id WHERE t.alias = ? AND uu.user_id = ?"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// queryRower single row query, implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Storage - sqlite storage for shorten links and users
+type Storage struct {
+	db *sql.DB
+}
+
+// NewStorage - sqlite storage Factory, schema must be created by RegisterMigrations
+func NewStorage(db *sql.DB) (*Storage, error) {
+	return &Storage{db: db}, nil
+}
+
+// RegisterMigrations register sqlite schema migrations of links and users
+func RegisterMigrations(m *migrations.Migrator) error {
+	return m.Register(migrationFS, "migrations")
+}
+
+// NewUser - Create new user
+func (s *Storage) NewUser(ctx context.Context) (*domain.User, error) {
+	user := &domain.User{}
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO "user" DEFAULT VALUES RETURNING id;`).Scan(&user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Add save shorten URL
+func (s *Storage) Add(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}(tx)
+
+	var isset bool
+	isset, err = s.add(ctx, tx, ent, user)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if isset {
+		return storage.ErrIssetURL
+	}
+
+	return nil
+}
+
+// AddBatch save shorten collection URL in one transaction, returns outcome per link in order of batch.
+// URL shortened before, also earlier in the same batch, is not saved and returns existing link
+func (s *Storage) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func(tx *sql.Tx) {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}(tx)
+
+	results := make([]*domain.BatchResult, 0, len(batch))
+
+	for _, v := range batch {
+		var res *domain.BatchResult
+		if res, err = s.addBatchItem(ctx, tx, v, user); err != nil {
+			return nil, err
+		}
+
+		results = append(results, res)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// BatchDelete delete shorten collection URL, returns deleted aliases owned by user
+func (s *Storage) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+
+	vals := make([]interface{}, 0, len(aliases)+2)
+	vals = append(vals, time.Now().UTC(), userID)
+	for _, v := range aliases {
+		vals = append(vals, v)
+	}
+
+	return s.queryAliases(ctx, `UPDATE shortener SET deleted = 1, deleted_at = coalesce(deleted_at, ?)
+		WHERE id IN (SELECT url_id FROM user_url WHERE user_id = ?)
+		AND alias IN (?`+strings.Repeat(",?", len(aliases)-1)+`) RETURNING alias;`,
+		vals...,
+	)
+}
+
+// Restore un-delete user shorten URL deleted since given time, expired links are not restored.
+// Returns restored aliases
+func (s *Storage) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+
+	vals := make([]interface{}, 0, len(aliases)+3)
+	vals = append(vals, userID, since.UTC(), time.Now().UTC())
+	for _, v := range aliases {
+		vals = append(vals, v)
+	}
+
+	return s.queryAliases(ctx, `UPDATE shortener SET deleted = 0, deleted_at = NULL
+		WHERE id IN (SELECT url_id FROM user_url WHERE user_id = ?)
+		AND deleted = 1 AND deleted_at >= ? AND (expires_at IS NULL OR expires_at > ?)
+		AND alias IN (?`+strings.Repeat(",?", len(aliases)-1)+`) RETURNING alias;`,
+		vals...,
+	)
+}
+
+// Purge remove links deleted before given time, owners and revisions are removed by cascade.
+// Returns removed aliases
+func (s *Storage) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	return s.queryAliases(ctx, `DELETE FROM shortener WHERE deleted = 1 AND deleted_at < ? RETURNING alias;`, before.UTC())
+}
+
+// queryAliases run query returning alias column
+func (s *Storage) queryAliases(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var aliases []string
+	for rows.Next() {
+		var alias string
+		if err = rows.Scan(&alias); err != nil {
+			return nil, err
+		}
+
+		aliases = append(aliases, alias)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return aliases, nil
+}
+
+// ReadUserURL read user shorten URL
+func (s *Storage) ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT s.url, s.alias, s.deleted, s.expires_at FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id WHERE uu.user_id = ?;",
+		user.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var batch domain.BatchURL
+	for rows.Next() {
+		var expiresAt sql.NullTime
+
+		u := &domain.URL{}
+		if err = rows.Scan(&u.URL, &u.Alias, &u.Deleted, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		u.ExpiresAt = expiresAt.Time
+
+		batch = append(batch, u)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return batch, nil
+}
+
+// ReadUserURLPage read page of user shorten URL ordered by creation time, one extra row is
+// selected to know whether next page exists
+func (s *Storage) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	where := []string{"uu.user_id = ?"}
+	vals := []interface{}{user.ID}
+
+	if !q.IncludeDeleted {
+		where = append(where, "s.deleted = 0")
+	}
+
+	if q.Contains != "" {
+		where = append(where, "instr(s.url, ?) > 0")
+		vals = append(vals, q.Contains)
+	}
+
+	order, cmp := "ASC", ">"
+	if q.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(s.created_at, s.alias) %s (?, ?)", cmp))
+		vals = append(vals, q.After.CreatedAt.UTC(), q.After.Alias)
+	}
+
+	vals = append(vals, q.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT s.url, s.alias, s.deleted, s.expires_at, s.created_at FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id WHERE %s ORDER BY s.created_at %s, s.alias %s LIMIT ?;",
+		strings.Join(where, " AND "), order, order,
+	), vals...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	page := &domain.UserURLPage{Items: make(domain.BatchURL, 0, q.Limit)}
+	for rows.Next() {
+		var expiresAt sql.NullTime
+
+		u := &domain.URL{}
+		if err = rows.Scan(&u.URL, &u.Alias, &u.Deleted, &expiresAt, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		u.ExpiresAt = expiresAt.Time
+
+		if len(page.Items) == q.Limit {
+			page.Next = domain.CursorOf(page.Items[len(page.Items)-1])
+			break
+		}
+
+		page.Items = append(page.Items, u)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return page, nil
+}
+
+// Read - read shorten URL
+func (s *Storage) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	return readOne(ctx, s.db, queryRead, alias)
+}
+
+// ReadByURL read shorten URL by URL
+func (s *Storage) ReadByURL(ctx context.Context, URL string) (*domain.URL, error) {
+	return readOne(ctx, s.db, queryReadByURL, URL)
+}
+
+// ReadUserAlias read user shorten URL by alias, deleted links are read too.
+// Returns storage.ErrNotFound when link is not owned by user
+func (s *Storage) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	return readOne(ctx, s.db, queryReadUser, alias, user.ID)
+}
+
+// readOne read link by query, returns storage.ErrNotFound when there is no link
+func readOne(ctx context.Context, q queryRower, query string, args ...any) (*domain.URL, error) {
+	var expiresAt sql.NullTime
+
+	ent := &domain.URL{}
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(&ent.URL, &ent.Alias, &ent.Deleted, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	ent.ExpiresAt = expiresAt.Time
+
+	return ent, nil
+}
+
+// Update change destination of user shorten URL and save previous destination to revision table.
+// Returns storage.ErrNotFound when link is not owned by user or deleted
+// and storage.ErrIssetURL when new destination is shortened by another link
+func (s *Storage) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}(tx)
+
+	var (
+		id  int64
+		old string
+	)
+
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT s.id, s.url FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id
+		WHERE s.alias = ? AND uu.user_id = ? AND s.deleted = 0`,
+		ent.Alias, user.ID,
+	).Scan(&id, &old)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+
+		return err
+	}
+
+	if old != ent.URL {
+		if _, err = tx.ExecContext(ctx, `UPDATE shortener SET url = ? WHERE id = ?`, ent.URL, id); err != nil {
+			if urlTaken(err) {
+				return storage.ErrIssetURL
+			}
+
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx, `INSERT INTO shortener_revision (url_id, user_id, url, changed_at) VALUES (?,?,?,?)`,
+			id, user.ID, old, time.Now().UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ReadRevisions read previous destinations of user shorten URL in order of change
+func (s *Storage) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(
+		ctx,
+		`SELECT s.id FROM shortener s INNER JOIN user_url uu on s.id = uu.url_id
+		WHERE s.alias = ? AND uu.user_id = ? AND s.deleted = 0`,
+		alias, user.ID,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx, `SELECT r.url, r.user_id, r.changed_at FROM shortener_revision r WHERE r.url_id = ? ORDER BY r.id`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := make([]*domain.Revision, 0)
+	for rows.Next() {
+		rev := &domain.Revision{Alias: alias}
+		if err = rows.Scan(&rev.URL, &rev.UserID, &rev.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return revisions, nil
+}
+
+// DeleteExpired soft delete links expired at time now, returns count of deleted links
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(
+		ctx, "UPDATE shortener SET deleted = 1, deleted_at = ? WHERE deleted = 0 AND expires_at <= ?;", now.UTC(), now.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// NextID return next alias sequence value, autoincrement never reuses deleted ids
+func (s *Storage) NextID(ctx context.Context) (int64, error) {
+	var id int64
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO alias_sequence DEFAULT VALUES RETURNING id;`).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = s.db.ExecContext(ctx, `DELETE FROM alias_sequence WHERE id = ?;`, id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CountURL return count of stored shorten URL
+func (s *Storage) CountURL(ctx context.Context) (int64, error) {
+	var n int64
+
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM shortener`).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// CountUsers return count of users
+func (s *Storage) CountUsers(ctx context.Context) (int64, error) {
+	var n int64
+
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM "user"`).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Close - close db
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// add insert URL in transaction and link it to user, returns true when URL already exists
+func (s *Storage) add(ctx context.Context, tx *sql.Tx, ent *domain.URL, user *domain.User) (bool, error) {
+	var id int64
+
+	err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO shortener (url, alias, expires_at, created_at) VALUES (?,?,?,?) ON CONFLICT (url) DO NOTHING RETURNING id`,
+		ent.URL, ent.Alias, nullTime(ent.ExpiresAt), time.Now().UTC(),
+	).Scan(&id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		if aliasTaken(err) {
+			return false, storage.ErrAliasTaken
+		}
+
+		return false, err
+	}
+
+	// URL shortened before stays owned by user created it
+	if id < 1 {
+		return true, nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx, `INSERT INTO user_url (user_id, url_id) VALUES (?,?) ON CONFLICT DO NOTHING`, user.ID, id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// addBatchItem save link of batch, URL is looked up first so taken alias of existing URL is not an error
+func (s *Storage) addBatchItem(ctx context.Context, tx *sql.Tx, ent *domain.URL, user *domain.User) (*domain.BatchResult, error) {
+	isset, err := readOne(ctx, tx, queryReadByURL, ent.URL)
+	if err == nil {
+		return &domain.BatchResult{URL: isset, Status: domain.BatchItemExisting}, nil
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	if _, err = s.add(ctx, tx, ent, user); err != nil {
+		return nil, err
+	}
+
+	return &domain.BatchResult{URL: ent, Status: domain.BatchItemCreated}, nil
+}
+
+// nullTime convert zero time to NULL, time stored in UTC to compare it as text
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// aliasTaken check error is unique violation of alias index
+func aliasTaken(err error) bool {
+	return uniqueViolation(err, "shortener.alias")
+}
+
+// urlTaken check error is unique violation of url index
+func urlTaken(err error) bool {
+	return uniqueViolation(err, "shortener.url")
+}
+
+func uniqueViolation(err error, column string) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique &&
+		strings.Contains(sqliteErr.Error(), column)
+}
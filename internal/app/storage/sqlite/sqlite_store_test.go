@@ -0,0 +1,371 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/config"
+	"github.com/korol8484/shortener/internal/app/db"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+func getStore(t *testing.T) *Storage {
+	p := path.Join(os.TempDir(), uuid.NewString())
+
+	conn, err := db.NewSQLiteDB(&config.App{DBDsn: db.SQLiteScheme + p})
+	require.NoError(t, err)
+
+	m := migrations.NewSQLiteMigrator(conn)
+	require.NoError(t, RegisterMigrations(m))
+	require.NoError(t, m.Up(context.Background()))
+
+	store, err := NewStorage(conn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = store.Close()
+		_ = os.Remove(p)
+	})
+
+	return store
+}
+
+func TestStorage_NewUser(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+
+	user, err = store.NewUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), user.ID)
+}
+
+func TestStorage_Add(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.ErrorIs(t, err, storage.ErrIssetURL)
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z"},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+	}, user)
+	require.NoError(t, err)
+
+	userURL, err := store.ReadUserURL(context.Background(), user)
+	require.NoError(t, err)
+	assert.Len(t, userURL, 3)
+}
+
+func TestStorage_Read(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	ent, err := store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.Equal(t, "http://www.ya.ru", ent.URL)
+	assert.False(t, ent.Deleted)
+
+	_, err = store.Read(context.Background(), "7A2S")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	ent, err = store.ReadByURL(context.Background(), "http://www.ya.ru")
+	require.NoError(t, err)
+	assert.Equal(t, "7A2S4z", ent.Alias)
+
+	_, err = store.ReadByURL(context.Background(), "http://www.ya1.ru")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestStorage_BatchDelete(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	other, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	deleted, err := store.BatchDelete(context.Background(), []string{"7A2S4z"}, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+
+	ent, err := store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+
+	deleted, err = store.BatchDelete(context.Background(), []string{"7A2S4z", "unknown"}, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"7A2S4z"}, deleted)
+
+	ent, err = store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+
+	userURL, err := store.ReadUserURL(context.Background(), user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 1)
+	assert.True(t, userURL[0].Deleted)
+}
+
+func TestStorage_AliasTaken(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z"}, user)
+	require.NoError(t, err)
+
+	err = store.Add(context.Background(), &domain.URL{URL: "http://www.ya1.ru", Alias: "7A2S4z"}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+		&domain.URL{URL: "http://www.ya3.ru", Alias: "7A2S4z"},
+	}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	_, err = store.ReadByURL(context.Background(), "http://www.ya2.ru")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestStorage_NextID(t *testing.T) {
+	store := getStore(t)
+
+	id, err := store.NextID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	id, err = store.NextID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
+
+func TestStorage_DeleteExpired(t *testing.T) {
+	store := getStore(t)
+	now := time.Now()
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	_, err = store.AddBatch(context.Background(), domain.BatchURL{
+		&domain.URL{URL: "http://www.ya.ru", Alias: "7A2S4z", ExpiresAt: now.Add(-time.Minute)},
+		&domain.URL{URL: "http://www.ya1.ru", Alias: "7A1S4z", ExpiresAt: now.Add(time.Hour)},
+		&domain.URL{URL: "http://www.ya2.ru", Alias: "7A3S4z"},
+	}, user)
+	require.NoError(t, err)
+
+	n, err := store.DeleteExpired(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	ent, err := store.Read(context.Background(), "7A2S4z")
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+
+	ent, err = store.Read(context.Background(), "7A1S4z")
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+	assert.True(t, now.Add(time.Hour).Equal(ent.ExpiresAt))
+}
+
+func TestStorage_Count(t *testing.T) {
+	store := getStore(t)
+
+	user, err := store.NewUser(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(context.Background(), &domain.URL{URL: "http://ya.ru", Alias: "a1"}, user))
+
+	n, err := store.CountURL(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = store.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestStorage_ReadUserURLPage(t *testing.T) {
+	store := getStore(t)
+	ctx := context.Background()
+
+	user, err := store.NewUser(ctx)
+	require.NoError(t, err)
+
+	for _, v := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://ya.ru/" + v, Alias: v}, user))
+	}
+
+	_, err = store.BatchDelete(ctx, []string{"b"}, user.ID)
+	require.NoError(t, err)
+
+	page, err := store.ReadUserURLPage(ctx, user, &domain.UserURLQuery{Limit: 1, IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "a", page.Items[0].Alias)
+	require.NotNil(t, page.Next)
+
+	page, err = store.ReadUserURLPage(ctx, user, &domain.UserURLQuery{Limit: 10, After: page.Next})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "c", page.Items[0].Alias)
+	assert.Nil(t, page.Next)
+
+	page, err = store.ReadUserURLPage(ctx, user, &domain.UserURLQuery{Limit: 10, Desc: true, Contains: "/a"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "a", page.Items[0].Alias)
+}
+
+func TestStorage_Update(t *testing.T) {
+	store := getStore(t)
+	ctx := context.Background()
+
+	user, err := store.NewUser(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://ya.ru/a", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://ya.ru/b", Alias: "b"}, user))
+
+	require.NoError(t, store.Update(ctx, &domain.URL{URL: "http://ya.ru/c", Alias: "a"}, user))
+
+	ent, err := store.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "http://ya.ru/c", ent.URL)
+
+	err = store.Update(ctx, &domain.URL{URL: "http://ya.ru/b", Alias: "a"}, user)
+	require.ErrorIs(t, err, storage.ErrIssetURL)
+
+	err = store.Update(ctx, &domain.URL{URL: "http://ya.ru/d", Alias: "a"}, &domain.User{ID: user.ID + 1})
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	revisions, err := store.ReadRevisions(ctx, "a", user)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "http://ya.ru/a", revisions[0].URL)
+	assert.Equal(t, user.ID, revisions[0].UserID)
+}
+
+func TestStorage_Restore(t *testing.T) {
+	store := getStore(t)
+	ctx := context.Background()
+
+	user, err := store.NewUser(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b", ExpiresAt: time.Now().Add(-time.Minute)}, user))
+
+	_, err = store.BatchDelete(ctx, []string{"a", "b"}, user.ID)
+	require.NoError(t, err)
+
+	restored, err := store.Restore(ctx, []string{"a"}, user.ID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	// expired link is not restored
+	restored, err = store.Restore(ctx, []string{"a", "b"}, user.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, restored)
+
+	ent, err := store.Read(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+}
+
+func TestStorage_Purge(t *testing.T) {
+	store := getStore(t)
+	ctx := context.Background()
+
+	user, err := store.NewUser(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya.ru", Alias: "a"}, user))
+	require.NoError(t, store.Add(ctx, &domain.URL{URL: "http://www.ya1.ru", Alias: "b"}, user))
+	require.NoError(t, store.Update(ctx, &domain.URL{URL: "http://www.ya.ru/new", Alias: "a"}, user))
+
+	_, err = store.BatchDelete(ctx, []string{"a"}, user.ID)
+	require.NoError(t, err)
+
+	purged, err := store.Purge(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	purged, err = store.Purge(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, purged)
+
+	_, err = store.Read(ctx, "a")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	userURL, err := store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, userURL, 1)
+
+	var n int
+	require.NoError(t, store.db.QueryRowContext(ctx, `SELECT count(*) FROM shortener_revision`).Scan(&n))
+	assert.Equal(t, 0, n)
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	ctx := context.Background()
+	p := path.Join(os.TempDir(), uuid.NewString())
+
+	conn, err := db.NewSQLiteDB(&config.App{DBDsn: db.SQLiteScheme + p})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = os.Remove(p)
+	})
+
+	m := migrations.NewSQLiteMigrator(conn)
+	require.NoError(t, RegisterMigrations(m))
+	require.ErrorIs(t, RegisterMigrations(m), migrations.ErrDuplicateVersion)
+
+	require.NoError(t, m.Up(ctx))
+	// applied versions are skipped
+	require.NoError(t, m.Up(ctx))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+
+	for _, st := range statuses {
+		assert.True(t, st.Applied)
+		assert.False(t, st.AppliedAt.IsZero())
+	}
+
+	require.NoError(t, m.Down(ctx, len(statuses)))
+
+	var n int
+	require.NoError(t, conn.QueryRowContext(ctx, `SELECT count(*) FROM sqlite_master WHERE name = 'shortener'`).Scan(&n))
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,427 @@
+// Package storagetest conformance test suite every handlers.Store implementation runs against,
+// keeps add, dedupe, batch, delete, ownership and read semantics same for all backends
+package storagetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+// Env store under test and factory of its users
+type Env struct {
+	Store   handlers.Store
+	NewUser func(t *testing.T) *domain.User
+}
+
+// UserCreator store of users links belong to
+type UserCreator interface {
+	NewUser(ctx context.Context) (*domain.User, error)
+}
+
+// UserFactory Env.NewUser backed by users store
+func UserFactory(users UserCreator) func(t *testing.T) *domain.User {
+	return func(t *testing.T) *domain.User {
+		user, err := users.NewUser(context.Background())
+		require.NoError(t, err)
+
+		return user
+	}
+}
+
+// Setup create Env for a test case, store may be shared by cases as links are unique per case
+type Setup func(t *testing.T) *Env
+
+// Run conformance suite against store created by setup
+func Run(t *testing.T, setup Setup) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, env *Env)
+	}{
+		{"Add", testAdd},
+		{"AddDuplicateURL", testAddDuplicateURL},
+		{"AddAliasTaken", testAddAliasTaken},
+		{"AddBatch", testAddBatch},
+		{"AddBatchExistingURL", testAddBatchExistingURL},
+		{"AddBatchDuplicateURL", testAddBatchDuplicateURL},
+		{"AddBatchAliasTaken", testAddBatchAliasTaken},
+		{"BatchDelete", testBatchDelete},
+		{"BatchDeleteNotOwner", testBatchDeleteNotOwner},
+		{"ReadUserURL", testReadUserURL},
+		{"ReadUserAlias", testReadUserAlias},
+		{"Update", testUpdate},
+		{"Restore", testRestore},
+		{"CountURL", testCountURL},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, setup(t))
+		})
+	}
+}
+
+// newURL link unique for test case, alias fits varchar(10) of postgresql schema
+func newURL() *domain.URL {
+	id := strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	return &domain.URL{URL: "http://" + id + ".ru/path", Alias: id[:10]}
+}
+
+func add(t *testing.T, env *Env, user *domain.User) *domain.URL {
+	u := newURL()
+	require.NoError(t, env.Store.Add(context.Background(), u, user))
+
+	return u
+}
+
+func aliasesOf(batch domain.BatchURL) []string {
+	aliases := make([]string, 0, len(batch))
+	for _, v := range batch {
+		aliases = append(aliases, v.Alias)
+	}
+
+	return aliases
+}
+
+func testAdd(t *testing.T, env *Env) {
+	ctx := context.Background()
+	u := add(t, env, env.NewUser(t))
+
+	ent, err := env.Store.Read(ctx, u.Alias)
+	require.NoError(t, err)
+	assert.Equal(t, u.URL, ent.URL)
+	assert.Equal(t, u.Alias, ent.Alias)
+	assert.False(t, ent.Deleted)
+
+	ent, err = env.Store.ReadByURL(ctx, u.URL)
+	require.NoError(t, err)
+	assert.Equal(t, u.Alias, ent.Alias)
+
+	_, err = env.Store.Read(ctx, newURL().Alias)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = env.Store.ReadByURL(ctx, newURL().URL)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testAddDuplicateURL(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+
+	dup := newURL()
+	dup.URL = u.URL
+
+	require.ErrorIs(t, env.Store.Add(ctx, dup, other), storage.ErrIssetURL)
+
+	ent, err := env.Store.ReadByURL(ctx, u.URL)
+	require.NoError(t, err)
+	assert.Equal(t, u.Alias, ent.Alias)
+
+	_, err = env.Store.Read(ctx, dup.Alias)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// shortening same URL does not make user its owner
+	batch, err := env.Store.ReadUserURL(ctx, other)
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+
+	deleted, err := env.Store.BatchDelete(ctx, []string{u.Alias}, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func testAddAliasTaken(t *testing.T, env *Env) {
+	u := add(t, env, env.NewUser(t))
+
+	taken := newURL()
+	taken.Alias = u.Alias
+
+	require.ErrorIs(t, env.Store.Add(context.Background(), taken, env.NewUser(t)), storage.ErrAliasTaken)
+
+	ent, err := env.Store.Read(context.Background(), u.Alias)
+	require.NoError(t, err)
+	assert.Equal(t, u.URL, ent.URL)
+}
+
+func testAddBatch(t *testing.T, env *Env) {
+	ctx := context.Background()
+	user := env.NewUser(t)
+	batch := domain.BatchURL{newURL(), newURL(), newURL()}
+
+	results, err := env.Store.AddBatch(ctx, batch, user)
+	require.NoError(t, err)
+	require.Len(t, results, len(batch))
+
+	for i, v := range batch {
+		assert.Equal(t, domain.BatchItemCreated, results[i].Status)
+		assert.Equal(t, v.Alias, results[i].URL.Alias)
+
+		ent, err := env.Store.Read(ctx, v.Alias)
+		require.NoError(t, err)
+		assert.Equal(t, v.URL, ent.URL)
+	}
+
+	owned, err := env.Store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, aliasesOf(batch), aliasesOf(owned))
+}
+
+func testAddBatchExistingURL(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+
+	// alias of existing URL is not checked, link is not saved
+	dup, fresh := newURL(), newURL()
+	dup.URL, dup.Alias = u.URL, u.Alias
+
+	results, err := env.Store.AddBatch(ctx, domain.BatchURL{dup, fresh}, other)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, domain.BatchItemExisting, results[0].Status)
+	assert.Equal(t, u.Alias, results[0].URL.Alias)
+	assert.Equal(t, u.URL, results[0].URL.URL)
+	assert.Equal(t, domain.BatchItemCreated, results[1].Status)
+	assert.Equal(t, fresh.Alias, results[1].URL.Alias)
+
+	ent, err := env.Store.ReadByURL(ctx, u.URL)
+	require.NoError(t, err)
+	assert.Equal(t, u.Alias, ent.Alias)
+
+	// shortening same URL does not make user its owner
+	owned, err := env.Store.ReadUserURL(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fresh.Alias}, aliasesOf(owned))
+}
+
+func testAddBatchDuplicateURL(t *testing.T, env *Env) {
+	ctx := context.Background()
+	user := env.NewUser(t)
+
+	first, dup := newURL(), newURL()
+	dup.URL = first.URL
+
+	results, err := env.Store.AddBatch(ctx, domain.BatchURL{first, dup}, user)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	// later item of the same URL refers to the first one
+	assert.Equal(t, domain.BatchItemCreated, results[0].Status)
+	assert.Equal(t, domain.BatchItemExisting, results[1].Status)
+	assert.Equal(t, first.Alias, results[1].URL.Alias)
+
+	_, err = env.Store.Read(ctx, dup.Alias)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	owned, err := env.Store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.Alias}, aliasesOf(owned))
+}
+
+func testAddBatchAliasTaken(t *testing.T, env *Env) {
+	ctx := context.Background()
+	user := env.NewUser(t)
+	u := add(t, env, env.NewUser(t))
+
+	fresh, taken := newURL(), newURL()
+	taken.Alias = u.Alias
+
+	_, err := env.Store.AddBatch(ctx, domain.BatchURL{fresh, taken}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	// batch is atomic, nothing is saved
+	_, err = env.Store.ReadByURL(ctx, fresh.URL)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// alias repeated in batch
+	other := newURL()
+	other.Alias = fresh.Alias
+
+	_, err = env.Store.AddBatch(ctx, domain.BatchURL{fresh, other}, user)
+	require.ErrorIs(t, err, storage.ErrAliasTaken)
+
+	owned, err := env.Store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	assert.Empty(t, owned)
+}
+
+func testBatchDelete(t *testing.T, env *Env) {
+	ctx := context.Background()
+	user := env.NewUser(t)
+	deleted, kept := add(t, env, user), add(t, env, user)
+	unknown := newURL().Alias
+
+	aliases, err := env.Store.BatchDelete(ctx, []string{deleted.Alias, unknown}, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{deleted.Alias}, aliases)
+
+	ent, err := env.Store.Read(ctx, deleted.Alias)
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+
+	ent, err = env.Store.ReadByURL(ctx, deleted.URL)
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+
+	ent, err = env.Store.Read(ctx, kept.Alias)
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+
+	// repeated delete is reported again as link still belongs to user
+	aliases, err = env.Store.BatchDelete(ctx, []string{deleted.Alias}, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{deleted.Alias}, aliases)
+
+	owned, err := env.Store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, owned, 2)
+
+	for _, v := range owned {
+		assert.Equal(t, v.Alias == deleted.Alias, v.Deleted, v.Alias)
+	}
+
+	require.ErrorIs(t, env.Store.Update(ctx, &domain.URL{Alias: deleted.Alias, URL: newURL().URL}, user), storage.ErrNotFound)
+}
+
+func testBatchDeleteNotOwner(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+
+	aliases, err := env.Store.BatchDelete(ctx, []string{u.Alias}, other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+
+	ent, err := env.Store.Read(ctx, u.Alias)
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+
+	owned, err := env.Store.ReadUserURL(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	assert.False(t, owned[0].Deleted)
+}
+
+func testReadUserURL(t *testing.T, env *Env) {
+	ctx := context.Background()
+	user, other := env.NewUser(t), env.NewUser(t)
+	first, second := add(t, env, user), add(t, env, user)
+	add(t, env, other)
+
+	owned, err := env.Store.ReadUserURL(ctx, user)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{first.Alias, second.Alias}, aliasesOf(owned))
+
+	owned, err = env.Store.ReadUserURL(ctx, env.NewUser(t))
+	require.NoError(t, err)
+	assert.Empty(t, owned)
+
+	page, err := env.Store.ReadUserURLPage(ctx, user, &domain.UserURLQuery{Limit: 10})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{first.Alias, second.Alias}, aliasesOf(page.Items))
+	assert.Nil(t, page.Next)
+}
+
+func testReadUserAlias(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+
+	ent, err := env.Store.ReadUserAlias(ctx, u.Alias, owner)
+	require.NoError(t, err)
+	assert.Equal(t, u.URL, ent.URL)
+	assert.False(t, ent.Deleted)
+
+	_, err = env.Store.ReadUserAlias(ctx, u.Alias, other)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = env.Store.ReadUserAlias(ctx, newURL().Alias, owner)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// deleted link is still owned
+	_, err = env.Store.BatchDelete(ctx, []string{u.Alias}, owner.ID)
+	require.NoError(t, err)
+
+	ent, err = env.Store.ReadUserAlias(ctx, u.Alias, owner)
+	require.NoError(t, err)
+	assert.True(t, ent.Deleted)
+}
+
+func testUpdate(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+	target := newURL().URL
+
+	require.ErrorIs(t, env.Store.Update(ctx, &domain.URL{Alias: u.Alias, URL: target}, other), storage.ErrNotFound)
+
+	_, err := env.Store.ReadRevisions(ctx, u.Alias, other)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.NoError(t, env.Store.Update(ctx, &domain.URL{Alias: u.Alias, URL: target}, owner))
+
+	ent, err := env.Store.Read(ctx, u.Alias)
+	require.NoError(t, err)
+	assert.Equal(t, target, ent.URL)
+
+	revisions, err := env.Store.ReadRevisions(ctx, u.Alias, owner)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, u.URL, revisions[0].URL)
+
+	taken := add(t, env, owner)
+	require.ErrorIs(t, env.Store.Update(ctx, &domain.URL{Alias: u.Alias, URL: taken.URL}, owner), storage.ErrIssetURL)
+}
+
+func testRestore(t *testing.T, env *Env) {
+	ctx := context.Background()
+	owner, other := env.NewUser(t), env.NewUser(t)
+	u := add(t, env, owner)
+	since := time.Now().Add(-time.Hour)
+
+	_, err := env.Store.BatchDelete(ctx, []string{u.Alias}, owner.ID)
+	require.NoError(t, err)
+
+	restored, err := env.Store.Restore(ctx, []string{u.Alias}, other.ID, since)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	restored, err = env.Store.Restore(ctx, []string{u.Alias}, owner.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	restored, err = env.Store.Restore(ctx, []string{u.Alias}, owner.ID, since)
+	require.NoError(t, err)
+	assert.Equal(t, []string{u.Alias}, restored)
+
+	ent, err := env.Store.Read(ctx, u.Alias)
+	require.NoError(t, err)
+	assert.False(t, ent.Deleted)
+}
+
+func testCountURL(t *testing.T, env *Env) {
+	ctx := context.Background()
+
+	before, err := env.Store.CountURL(ctx)
+	require.NoError(t, err)
+
+	user := env.NewUser(t)
+	add(t, env, user)
+	add(t, env, user)
+
+	after, err := env.Store.CountURL(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before+2, after)
+}
@@ -0,0 +1,216 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/korol8484/shortener/internal/app/alias"
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/handlers"
+	"github.com/korol8484/shortener/internal/app/storage"
+)
+
+// scope instrumentation scope of decorators
+const scope = "github.com/korol8484/shortener/internal/app/tracing"
+
+// start child span of store operation, tracer is taken from global provider on every call
+// so provider installed after decorator is created is used
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+// end record error of operation and end span, not found is a result of lookup and not an error
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Store decorator of handlers.Store with child span per operation,
+// optional capabilities of base store are forwarded
+type Store struct {
+	baseStore handlers.Store
+}
+
+// NewStore Factory
+func NewStore(baseStore handlers.Store) *Store {
+	return &Store{baseStore: baseStore}
+}
+
+// Add save shorten URL
+func (s *Store) Add(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	ctx, span := start(ctx, "store.Add", attribute.String("shortener.alias", ent.Alias))
+	err := s.baseStore.Add(ctx, ent, user)
+	end(span, err)
+
+	return err
+}
+
+// Read - read shorten URL by alias
+func (s *Store) Read(ctx context.Context, alias string) (*domain.URL, error) {
+	ctx, span := start(ctx, "store.Read", attribute.String("shortener.alias", alias))
+	u, err := s.baseStore.Read(ctx, alias)
+	end(span, err)
+
+	return u, err
+}
+
+// ReadByURL read shorten URL by URL
+func (s *Store) ReadByURL(ctx context.Context, URL string) (*domain.URL, error) {
+	ctx, span := start(ctx, "store.ReadByURL")
+	u, err := s.baseStore.ReadByURL(ctx, URL)
+	end(span, err)
+
+	return u, err
+}
+
+// AddBatch save shorten collection URL
+func (s *Store) AddBatch(ctx context.Context, batch domain.BatchURL, user *domain.User) ([]*domain.BatchResult, error) {
+	ctx, span := start(ctx, "store.AddBatch", attribute.Int("shortener.batch_size", len(batch)))
+	res, err := s.baseStore.AddBatch(ctx, batch, user)
+	end(span, err)
+
+	return res, err
+}
+
+// ReadUserURL read user shorten URL
+func (s *Store) ReadUserURL(ctx context.Context, user *domain.User) (domain.BatchURL, error) {
+	ctx, span := start(ctx, "store.ReadUserURL")
+	res, err := s.baseStore.ReadUserURL(ctx, user)
+	end(span, err)
+
+	return res, err
+}
+
+// ReadUserURLPage read page of user shorten URL
+func (s *Store) ReadUserURLPage(ctx context.Context, user *domain.User, q *domain.UserURLQuery) (*domain.UserURLPage, error) {
+	ctx, span := start(ctx, "store.ReadUserURLPage")
+	res, err := s.baseStore.ReadUserURLPage(ctx, user, q)
+	end(span, err)
+
+	return res, err
+}
+
+// BatchDelete delete shorten collection URL
+func (s *Store) BatchDelete(ctx context.Context, aliases []string, userID int64) ([]string, error) {
+	ctx, span := start(ctx, "store.BatchDelete", attribute.Int("shortener.batch_size", len(aliases)))
+	res, err := s.baseStore.BatchDelete(ctx, aliases, userID)
+	end(span, err)
+
+	return res, err
+}
+
+// Restore un-delete user shorten URL
+func (s *Store) Restore(ctx context.Context, aliases []string, userID int64, since time.Time) ([]string, error) {
+	ctx, span := start(ctx, "store.Restore", attribute.Int("shortener.batch_size", len(aliases)))
+	res, err := s.baseStore.Restore(ctx, aliases, userID, since)
+	end(span, err)
+
+	return res, err
+}
+
+// Update change destination of user shorten URL
+func (s *Store) Update(ctx context.Context, ent *domain.URL, user *domain.User) error {
+	ctx, span := start(ctx, "store.Update", attribute.String("shortener.alias", ent.Alias))
+	err := s.baseStore.Update(ctx, ent, user)
+	end(span, err)
+
+	return err
+}
+
+// ReadUserAlias read user shorten URL by alias
+func (s *Store) ReadUserAlias(ctx context.Context, alias string, user *domain.User) (*domain.URL, error) {
+	ctx, span := start(ctx, "store.ReadUserAlias", attribute.String("shortener.alias", alias))
+	u, err := s.baseStore.ReadUserAlias(ctx, alias, user)
+	end(span, err)
+
+	return u, err
+}
+
+// ReadRevisions read previous destinations of user shorten URL
+func (s *Store) ReadRevisions(ctx context.Context, alias string, user *domain.User) ([]*domain.Revision, error) {
+	ctx, span := start(ctx, "store.ReadRevisions", attribute.String("shortener.alias", alias))
+	res, err := s.baseStore.ReadRevisions(ctx, alias, user)
+	end(span, err)
+
+	return res, err
+}
+
+// CountURL return count of stored shorten URL
+func (s *Store) CountURL(ctx context.Context) (int64, error) {
+	ctx, span := start(ctx, "store.CountURL")
+	res, err := s.baseStore.CountURL(ctx)
+	end(span, err)
+
+	return res, err
+}
+
+// Purge remove links deleted before given time from base store
+func (s *Store) Purge(ctx context.Context, before time.Time) ([]string, error) {
+	p, ok := s.baseStore.(handlers.Purgeable)
+	if !ok {
+		return nil, errors.New("base store can't purge links")
+	}
+
+	ctx, span := start(ctx, "store.Purge")
+	res, err := p.Purge(ctx, before)
+	end(span, err)
+
+	return res, err
+}
+
+// DeleteExpired soft delete expired links in base store
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	exp, ok := s.baseStore.(handlers.Expirable)
+	if !ok {
+		return 0, errors.New("base store can't delete expired links")
+	}
+
+	ctx, span := start(ctx, "store.DeleteExpired")
+	res, err := exp.DeleteExpired(ctx, now)
+	end(span, err)
+
+	return res, err
+}
+
+// NextID return next alias sequence value from base store
+func (s *Store) NextID(ctx context.Context) (int64, error) {
+	seq, ok := s.baseStore.(alias.Sequence)
+	if !ok {
+		return 0, errors.New("base store has no sequence")
+	}
+
+	ctx, span := start(ctx, "store.NextID")
+	res, err := seq.NextID(ctx)
+	end(span, err)
+
+	return res, err
+}
+
+// Compact run compaction of base store
+func (s *Store) Compact() error {
+	c, ok := s.baseStore.(handlers.Compactable)
+	if !ok {
+		return errors.New("base store can't compact")
+	}
+
+	_, span := start(context.Background(), "store.Compact")
+	err := c.Compact()
+	end(span, err)
+
+	return err
+}
+
+// Close - base store is closed by its owner
+func (s *Store) Close() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/storage"
+	"github.com/korol8484/shortener/internal/app/storage/memory"
+	"github.com/korol8484/shortener/internal/app/storage/storagetest"
+	userStorage "github.com/korol8484/shortener/internal/app/user/storage"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) *storagetest.Env {
+		return &storagetest.Env{Store: NewStore(memory.NewMemStore()), NewUser: storagetest.UserFactory(userStorage.NewMemoryStore())}
+	})
+}
+
+// failStore fails Update
+type failStore struct {
+	*memory.MemStore
+}
+
+func (f *failStore) Update(_ context.Context, _ *domain.URL, _ *domain.User) error {
+	return errors.New("db down")
+}
+
+func TestStore_Spans(t *testing.T) {
+	sr := withRecorder(t)
+	s := NewStore(&failStore{MemStore: memory.NewMemStore()})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+
+	require.NoError(t, s.Add(ctx, &domain.URL{URL: "http://ya.ru", Alias: "a"}, &domain.User{ID: 1}))
+
+	_, err := s.Read(ctx, "b")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.Error(t, s.Update(ctx, &domain.URL{URL: "http://ya1.ru", Alias: "a"}, &domain.User{ID: 1}))
+
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 4)
+
+	for _, span := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+
+	assert.Equal(t, "store.Add", spans[0].Name())
+	assert.Equal(t, "store.Read", spans[1].Name())
+	// not found is not an error
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, "store.Update", spans[2].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
+
+func TestUserRepository(t *testing.T) {
+	sr := withRecorder(t)
+	rep := NewUserRepository(userStorage.NewMemoryStore())
+
+	_, err := rep.NewUser(context.Background())
+	require.NoError(t, err)
+
+	count, err := rep.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "users.NewUser", spans[0].Name())
+	assert.Equal(t, "users.CountUsers", spans[1].Name())
+}
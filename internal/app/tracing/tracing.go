@@ -0,0 +1,120 @@
+// Package tracing OpenTelemetry tracing of service: provider with OTLP or stdout exporter
+// and tracing decorators of stores
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName name of service in traces
+const ServiceName = "shortener"
+
+// exporters
+const (
+	ExporterNone   = ""
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// ErrUnknownExporter - Ошибка что задан неизвестный экспортер трейсов
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Config tracing configuration
+type Config interface {
+	GetTraceExporter() string
+	GetTraceEndpoint() string
+	GetTraceInsecure() bool
+	GetTraceFile() string
+	GetTraceSampleRatio() float64
+}
+
+// Setup install global tracer provider exporting spans by configured exporter and W3C trace context propagator.
+// Returned shutdown flushes spans left in batch, without exporter nothing is installed and spans are not recorded
+func Setup(ctx context.Context, cfg Config, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.GetTraceExporter() == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.GetTraceSampleRatio()))),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+
+		return err
+	}, nil
+}
+
+// newExporter OTLP exporter over gRPC or stdout exporter writing to file, closer is not nil for file
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.GetTraceExporter() {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.GetTraceEndpoint())}
+		if cfg.GetTraceInsecure() {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return exporter, nil, nil
+	case ExporterStdout:
+		if cfg.GetTraceFile() == "" {
+			exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+			return exporter, nil, err
+		}
+
+		f, err := os.OpenFile(cfg.GetTraceFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, nil, err
+		}
+
+		return exporter, f, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s, expected %s or %s", ErrUnknownExporter, cfg.GetTraceExporter(), ExporterOTLP, ExporterStdout)
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/korol8484/shortener/internal/app/config"
+)
+
+// withRecorder install global provider recording spans for test
+func withRecorder(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+	})
+
+	return sr
+}
+
+func TestSetup(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), &config.App{}, "test")
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := Setup(context.Background(), &config.App{TraceExporter: "zipkin"}, "test")
+		assert.ErrorIs(t, err, ErrUnknownExporter)
+	})
+
+	t.Run("stdout_file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "spans.json")
+
+		shutdown, err := Setup(context.Background(), &config.App{
+			TraceExporter:    ExporterStdout,
+			TraceFile:        file,
+			TraceSampleRatio: 1,
+		}, "test")
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(context.Background(), "operation")
+		span.End()
+
+		require.NoError(t, shutdown(context.Background()))
+
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), `"Name":"operation"`)
+		assert.Contains(t, string(b), ServiceName)
+	})
+
+	t.Run("otlp", func(t *testing.T) {
+		// exporter connects lazily, so collector is not needed to start
+		shutdown, err := Setup(context.Background(), &config.App{
+			TraceExporter:    ExporterOTLP,
+			TraceEndpoint:    "localhost:1",
+			TraceInsecure:    true,
+			TraceSampleRatio: 1,
+		}, "test")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = shutdown(ctx)
+	})
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/korol8484/shortener/internal/app/domain"
+)
+
+// UserStore repository creating users for tokens and counting them
+type UserStore interface {
+	NewUser(ctx context.Context) (*domain.User, error)
+	CountUsers(ctx context.Context) (int64, error)
+}
+
+// UserRepository decorator of user repository with child span per operation
+type UserRepository struct {
+	base UserStore
+}
+
+// NewUserRepository Factory
+func NewUserRepository(base UserStore) *UserRepository {
+	return &UserRepository{base: base}
+}
+
+// NewUser create user in base repository
+func (u *UserRepository) NewUser(ctx context.Context) (*domain.User, error) {
+	ctx, span := start(ctx, "users.NewUser")
+	user, err := u.base.NewUser(ctx)
+	end(span, err)
+
+	return user, err
+}
+
+// CountUsers return count of users in base repository
+func (u *UserRepository) CountUsers(ctx context.Context) (int64, error) {
+	ctx, span := start(ctx, "users.CountUsers")
+	res, err := u.base.CountUsers(ctx)
+	end(span, err)
+
+	return res, err
+}
@@ -3,27 +3,30 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"sync"
 
 	"github.com/korol8484/shortener/internal/app/domain"
+	"github.com/korol8484/shortener/internal/app/migrations"
 )
 
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
 // DBStorage User storage
 type DBStorage struct {
 	mu sync.RWMutex
 	db *sql.DB
 }
 
-// NewStorage - User storage factory
+// NewStorage - User storage factory, schema must be created by RegisterMigrations
 func NewStorage(db *sql.DB) (*DBStorage, error) {
-	storage := &DBStorage{db: db}
-
-	err := storage.migrate(context.Background())
-	if err != nil {
-		return nil, err
-	}
+	return &DBStorage{db: db}, nil
+}
 
-	return storage, nil
+// RegisterMigrations register user schema migrations
+func RegisterMigrations(m *migrations.Migrator) error {
+	return m.Register(migrationFS, "migrations")
 }
 
 // NewUser - Create new user in DB
@@ -47,34 +50,13 @@ func (d *DBStorage) NewUser(ctx context.Context) (*domain.User, error) {
 	return user, nil
 }
 
-func (d *DBStorage) migrate(ctx context.Context) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	tx, err := d.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer func(tx *sql.Tx) {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}(tx)
-
-	_, err = tx.ExecContext(ctx, `
-	create table if not exists "user"
-	(
-		id    bigserial
-			constraint user_pk
-				primary key
-	);`)
-	if err != nil {
-		return err
-	}
+// CountUsers return count of users
+func (d *DBStorage) CountUsers(ctx context.Context) (int64, error) {
+	var n int64
 
-	if err = tx.Commit(); err != nil {
-		return err
+	if err := d.db.QueryRowContext(ctx, `SELECT count(*) FROM "user"`).Scan(&n); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return n, nil
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/korol8484/shortener/internal/app/migrations"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"os"
@@ -34,10 +35,6 @@ func run(m *testing.M) (int, error) {
 		return -1, err
 	}
 
-	mock.ExpectBegin()
-	mock.ExpectExec("create table if not exists \"user\"").WithoutArgs().WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
-
 	store, err = NewStorage(db)
 	if err != nil {
 		return -1, err
@@ -61,3 +58,19 @@ func TestDBStorage_NewUser(t *testing.T) {
 
 	require.Error(t, err)
 }
+
+func TestDBStorage_CountUsers(t *testing.T) {
+	mock.ExpectQuery("SELECT count").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	n, err := store.CountUsers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestRegisterMigrations(t *testing.T) {
+	m := migrations.NewMigrator(nil)
+
+	require.NoError(t, RegisterMigrations(m))
+	require.ErrorIs(t, RegisterMigrations(m), migrations.ErrDuplicateVersion)
+}
@@ -23,3 +23,8 @@ func (m *MemoryStore) NewUser(ctx context.Context) (*domain.User, error) {
 		ID: atomic.AddInt64(&m.id, 1),
 	}, nil
 }
+
+// CountUsers return count of users created in memory
+func (m *MemoryStore) CountUsers(ctx context.Context) (int64, error) {
+	return atomic.LoadInt64(&m.id), nil
+}
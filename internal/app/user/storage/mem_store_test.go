@@ -15,4 +15,9 @@ func TestMemoryStore_NewUser(t *testing.T) {
 	if user.ID != 1 {
 		t.Fatal("user ID not valid, expexted 1")
 	}
+
+	n, err := store.CountUsers(context.Background())
+	if err != nil || n != 1 {
+		t.Fatal("users count not valid, expexted 1")
+	}
 }
@@ -0,0 +1,6 @@
+create table if not exists "user"
+(
+    id bigserial
+        constraint user_pk
+            primary key
+);
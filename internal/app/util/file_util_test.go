@@ -0,0 +1,29 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0666))
+	require.NoError(t, WriteFileAtomic(path, []byte("new")))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(b))
+
+	// temp file is not left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "data.json"), []byte("new")))
+}